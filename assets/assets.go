@@ -26,4 +26,12 @@ type VirtualMachine struct {
 type VirtualPrivateCloud struct {
 	Name  string `json:"name"`
 	VpcID string `json:"vpcID"`
+	// PeeredVpcs are the VPCs this one is peered with
+	PeeredVpcs []VpcReference `json:"peeredVpcs,omitempty"`
+}
+
+// VpcReference points to a VPC by its ID. The namespace is only set for a VPC from another inventory; it is kept apart from the ID, since IDs such as ARNs can hold any character
+type VpcReference struct {
+	Namespace string `json:"namespace,omitempty"`
+	VpcID     string `json:"vpcID"`
 }
@@ -1,9 +1,13 @@
 package assets
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/mimatache/cyscale/internal/graph"
 )
@@ -15,32 +19,168 @@ const (
 	VirtualMacineType = "vm"
 )
 
-// NewManager creates a new instance of an asset manager, allong with loading the data that will be used by it
-func NewManager(graph *graph.Graph, vpcData, sgData, interfaceData, vmData []byte) (*Manager, error) {
-	m := &Manager{
-		graph: graph,
+// NodeStyles are used to draw each type of asset when exporting the graph
+var NodeStyles = map[string]graph.NodeStyle{
+	VirtualMacineType: {Shape: "box", Color: "lightblue"},
+	InterfaceType:     {Shape: "ellipse", Color: "palegreen"},
+	SecurityGroupType: {Shape: "octagon", Color: "lightsalmon"},
+	VpcType:           {Shape: "folder", Color: "lightgoldenrod"},
+}
+
+// bodyTypes maps each type of asset to the Go type its data is decoded into
+var bodyTypes = map[string]interface{}{
+	InterfaceType:     Interface{},
+	VpcType:           VirtualPrivateCloud{},
+	SecurityGroupType: SecurityGroup{},
+	VirtualMacineType: VirtualMachine{},
+}
+
+// edgeKinds are the only relationships that can exist between assets
+var edgeKinds = []graph.EdgeKind{
+	{FromLabel: VirtualMacineType, Label: "part_of", ToLabel: VpcType},
+	{FromLabel: VirtualMacineType, Label: "part_of", ToLabel: SecurityGroupType},
+	{FromLabel: VirtualMacineType, Label: "using", ToLabel: InterfaceType},
+	{FromLabel: InterfaceType, Label: "part_of", ToLabel: VpcType},
+	{FromLabel: InterfaceType, Label: "part_of", ToLabel: SecurityGroupType},
+	{FromLabel: SecurityGroupType, Label: "part_of", ToLabel: VpcType},
+	{FromLabel: VpcType, Label: "peered_with", ToLabel: VpcType},
+}
+
+// indexedFields are the fields of each type of asset that the rules look assets up by
+var indexedFields = map[string][]string{
+	SecurityGroupType: {"direction", "ipList", "exposedPorts"},
+	VirtualMacineType: {"vpcID"},
+	InterfaceType:     {"vpcID"},
+}
+
+// configureGraph makes the graph check the data of every asset against its type, reject relationships that can not exist between assets, and index the fields used by the rules
+func configureGraph(grf *graph.Graph) error {
+	for label, v := range bodyTypes {
+		if err := grf.RegisterBodyType(label, v); err != nil {
+			return fmt.Errorf("could not register the type of %s assets; %w", label, err)
+		}
+	}
+	for _, kind := range edgeKinds {
+		grf.AllowRelationship(kind.FromLabel, kind.Label, kind.ToLabel)
 	}
+	for label, paths := range indexedFields {
+		for _, path := range paths {
+			grf.IndexField(label, path)
+		}
+	}
+	return nil
+}
 
-	if err := m.loadVPCs(vpcData); err != nil {
+// NewManager creates a new instance of an asset manager, allong with loading the data that will be used by it in the default namespace.
+// All the data is loaded in a single batch, so if any of it can not be loaded, the graph is left unchanged
+func NewManager(grf *graph.Graph, vpcData, sgData, interfaceData, vmData []byte) (*Manager, error) {
+	m, err := NewManagerFromGraph(grf)
+	if err != nil {
 		return nil, err
 	}
-	if err := m.loadSGs(sgData); err != nil {
+	if err := m.Load("", vpcData, sgData, interfaceData, vmData); err != nil {
 		return nil, err
 	}
-	if err := m.loadInterfaces(interfaceData); err != nil {
+	return m, nil
+}
+
+// NewManagerFromGraph creates an asset manager on top of a graph that already contains the assets (e.g.: a graph loaded from a snapshot).
+// An error is returned if the data of any of the assets does not match its type
+func NewManagerFromGraph(grf *graph.Graph) (*Manager, error) {
+	if err := configureGraph(grf); err != nil {
 		return nil, err
 	}
-	if err := m.loadVMs(vmData); err != nil {
+	return &Manager{
+		graph: grf,
+	}, nil
+}
+
+// NewManagerWithStore creates an asset manager on top of a graph kept in the given store, e.g.: a graph.NewTempDiskStore for inventories whose asset data does not fit in memory.
+// Assets already in the store are kept, and their data is checked as with NewManagerFromGraph
+func NewManagerWithStore(store graph.Store) (*Manager, error) {
+	grf, err := graph.NewWithStore(store)
+	if err != nil {
 		return nil, err
 	}
-	return m, nil
+	return NewManagerFromGraph(grf)
 }
 
+// Manager runs the checks on the assets held in a graph. Each check runs against a view of the graph, so inventories can be loaded while checks run
 type Manager struct {
 	graph *graph.Graph
+	// namespaces limit the assets that are checked; if empty, all assets are
+	namespaces []string
+}
+
+// Load adds an inventory to the graph, in the given namespace (e.g.: the account and region the inventory comes from).
+// Assets in the inventory reference each other by ID; a peered VPC from another namespace also names that namespace.
+// An asset that was already loaded takes the data and relationships of the new inventory, instead of keeping the ones it had.
+// All the data is loaded in a single batch, so if any of it can not be loaded, the graph is left unchanged
+func (m *Manager) Load(namespace string, vpcData, sgData, interfaceData, vmData []byte) error {
+	if strings.Contains(namespace, graph.NamespaceSeparator) {
+		return fmt.Errorf("namespace %s can not contain '%s'", namespace, graph.NamespaceSeparator)
+	}
+	err := m.graph.Batch(func(tx *graph.Tx) error {
+		if err := loadVPCs(tx, namespace, vpcData); err != nil {
+			return err
+		}
+		if err := loadSGs(tx, namespace, sgData); err != nil {
+			return err
+		}
+		if err := loadInterfaces(tx, namespace, interfaceData); err != nil {
+			return err
+		}
+		return loadVMs(tx, namespace, vmData)
+	})
+	if err != nil {
+		return err
+	}
+	return storeErr(m.graph)
+}
+
+// InNamespaces returns a manager over the same graph, that only checks the assets in the given namespaces.
+// Connections between assets can still pass through other namespaces
+func (m *Manager) InNamespaces(namespaces ...string) *Manager {
+	return &Manager{
+		graph:      m.graph,
+		namespaces: namespaces,
+	}
+}
+
+// Graph returns the graph holding the assets
+func (m *Manager) Graph() *graph.Graph {
+	return m.graph
+}
+
+// SecurityGroup decodes the data of a security group asset
+func (m *Manager) SecurityGroup(node graph.Node) (SecurityGroup, error) {
+	sg := SecurityGroup{}
+	err := m.graph.DecodeBody(node, &sg)
+	return sg, err
+}
+
+// VirtualMachine decodes the data of a VM asset
+func (m *Manager) VirtualMachine(node graph.Node) (VirtualMachine, error) {
+	vm := VirtualMachine{}
+	err := m.graph.DecodeBody(node, &vm)
+	return vm, err
+}
+
+// Interface decodes the data of a network interface asset
+func (m *Manager) Interface(node graph.Node) (Interface, error) {
+	intf := Interface{}
+	err := m.graph.DecodeBody(node, &intf)
+	return intf, err
+}
+
+// VirtualPrivateCloud decodes the data of a VPC asset
+func (m *Manager) VirtualPrivateCloud(node graph.Node) (VirtualPrivateCloud, error) {
+	vpc := VirtualPrivateCloud{}
+	err := m.graph.DecodeBody(node, &vpc)
+	return vpc, err
 }
 
-func (m *Manager) loadInterfaces(data []byte) error {
+func loadInterfaces(tx *graph.Tx, namespace string, data []byte) error {
 	interfaces := []Interface{}
 	if err := json.Unmarshal(data, &interfaces); err != nil {
 		return fmt.Errorf("could not unmarshal interfaces; %w", err)
@@ -50,36 +190,27 @@ func (m *Manager) loadInterfaces(data []byte) error {
 		if err != nil {
 			return fmt.Errorf("could not marshal interface; %w", err)
 		}
-		node := m.graph.InsertNode(v.NetworkInterfaceID, InterfaceType, interfaceBody)
-
-		vpcs := m.graph.ListNodes(graph.FilterNodesByName(v.VpcID))
-		if len(vpcs) == 0 {
-			n := m.graph.InsertNode(v.VpcID, VpcType, []byte{})
-			vpcs = append(vpcs, n)
+		node, err := tx.UpsertNodeInNamespace(namespace, v.NetworkInterfaceID, InterfaceType, interfaceBody)
+		if err != nil {
+			return err
 		}
-		for _, vpc := range vpcs {
-			if _, err := m.graph.AddRelationship(node.GetID(), vpc.GetID(), "part_of"); err != nil {
-				return err
-			}
+		kept := map[string]struct{}{}
+		if err := relate(tx, node, "", v.VpcID, VpcType, "part_of", nil, kept); err != nil {
+			return err
 		}
-
 		for _, sg := range v.SecurityGroupIDs {
-			sgs := m.graph.ListNodes(graph.FilterNodesByName(sg))
-			if len(sgs) == 0 {
-				n := m.graph.InsertNode(sg, SecurityGroupType, []byte{})
-				sgs = append(sgs, n)
-			}
-			for _, sgNode := range sgs {
-				if _, err := m.graph.AddRelationship(node.GetID(), sgNode.GetID(), "part_of"); err != nil {
-					return err
-				}
+			if err := relate(tx, node, "", sg, SecurityGroupType, "part_of", nil, kept); err != nil {
+				return err
 			}
 		}
+		if err := dropStale(tx, node, kept); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (m *Manager) loadVPCs(data []byte) error {
+func loadVPCs(tx *graph.Tx, namespace string, data []byte) error {
 	vpcs := []VirtualPrivateCloud{}
 	if err := json.Unmarshal(data, &vpcs); err != nil {
 		return fmt.Errorf("could not unmarshal interfaces; %w", err)
@@ -89,12 +220,24 @@ func (m *Manager) loadVPCs(data []byte) error {
 		if err != nil {
 			return fmt.Errorf("could not marshal interface; %w", err)
 		}
-		m.graph.InsertNode(v.VpcID, VpcType, vpcBody)
+		node, err := tx.UpsertNodeInNamespace(namespace, v.VpcID, VpcType, vpcBody)
+		if err != nil {
+			return err
+		}
+		kept := map[string]struct{}{}
+		for _, peer := range v.PeeredVpcs {
+			if err := relate(tx, node, peer.Namespace, peer.VpcID, VpcType, "peered_with", nil, kept); err != nil {
+				return err
+			}
+		}
+		if err := dropStale(tx, node, kept); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (m *Manager) loadVMs(data []byte) error {
+func loadVMs(tx *graph.Tx, namespace string, data []byte) error {
 	vms := []VirtualMachine{}
 	if err := json.Unmarshal(data, &vms); err != nil {
 		return fmt.Errorf("could not unmarshal vms; %w", err)
@@ -104,49 +247,32 @@ func (m *Manager) loadVMs(data []byte) error {
 		if err != nil {
 			return fmt.Errorf("could not marshal vms; %w", err)
 		}
-		node := m.graph.InsertNode(v.Name, VirtualMacineType, vmBody)
-
-		vpcs := m.graph.ListNodes(graph.FilterNodesByName(v.VpcID))
-		if len(vpcs) == 0 {
-			n := m.graph.InsertNode(v.VpcID, VpcType, []byte{})
-			vpcs = append(vpcs, n)
+		node, err := tx.UpsertNodeInNamespace(namespace, v.Name, VirtualMacineType, vmBody)
+		if err != nil {
+			return err
 		}
-		for _, vpc := range vpcs {
-			if _, err := m.graph.AddRelationship(node.GetID(), vpc.GetID(), "part_of"); err != nil {
-				return err
-			}
+		kept := map[string]struct{}{}
+		if err := relate(tx, node, "", v.VpcID, VpcType, "part_of", nil, kept); err != nil {
+			return err
 		}
-
 		for _, sg := range v.SecurityGroupIDs {
-			sgs := m.graph.ListNodes(graph.FilterNodesByName(sg))
-			if len(sgs) == 0 {
-				n := m.graph.InsertNode(sg, SecurityGroupType, []byte{})
-				sgs = append(sgs, n)
-			}
-			for _, sgNode := range sgs {
-				if _, err := m.graph.AddRelationship(node.GetID(), sgNode.GetID(), "part_of"); err != nil {
-					return err
-				}
+			if err := relate(tx, node, "", sg, SecurityGroupType, "part_of", nil, kept); err != nil {
+				return err
 			}
 		}
-
 		for _, intfID := range v.NetworkInterfaceIDs {
-			intfs := m.graph.ListNodes(graph.FilterNodesByName(intfID))
-			if len(intfs) == 0 {
-				n := m.graph.InsertNode(intfID, SecurityGroupType, []byte{})
-				intfs = append(intfs, n)
-			}
-			for _, intfNode := range intfs {
-				if _, err := m.graph.AddRelationship(node.GetID(), intfNode.GetID(), "using"); err != nil {
-					return err
-				}
+			if err := relate(tx, node, "", intfID, InterfaceType, "using", nil, kept); err != nil {
+				return err
 			}
 		}
+		if err := dropStale(tx, node, kept); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (m *Manager) loadSGs(data []byte) error {
+func loadSGs(tx *graph.Tx, namespace string, data []byte) error {
 	sgs := []SecurityGroup{}
 	if err := json.Unmarshal(data, &sgs); err != nil {
 		return fmt.Errorf("could not unmarshal sgs; %w", err)
@@ -156,92 +282,356 @@ func (m *Manager) loadSGs(data []byte) error {
 		if err != nil {
 			return fmt.Errorf("could not marshal sgs; %w", err)
 		}
-		node := m.graph.InsertNode(v.GroupID, SecurityGroupType, sgBody)
+		node, err := tx.UpsertNodeInNamespace(namespace, v.GroupID, SecurityGroupType, sgBody)
+		if err != nil {
+			return err
+		}
+		kept := map[string]struct{}{}
+		if err := relate(tx, node, "", v.VpcID, VpcType, "part_of", ruleProperties(v), kept); err != nil {
+			return err
+		}
+		if err := dropStale(tx, node, kept); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		vpcs := m.graph.ListNodes(graph.FilterNodesByName(v.VpcID))
-		if len(vpcs) == 0 {
-			n := m.graph.InsertNode(v.VpcID, VpcType, []byte{})
-			vpcs = append(vpcs, n)
+// ruleProperties describes the rule of the security group, which is set on its relationship to the VPC, e.g.: to find the rules allowing a port with FilterRelByProperty
+func ruleProperties(sg SecurityGroup) map[string]string {
+	ports := make([]string, 0, len(sg.ExposedPorts))
+	for _, port := range sg.ExposedPorts {
+		ports = append(ports, strconv.Itoa(port))
+	}
+	return map[string]string{
+		"direction": sg.Direction,
+		"ports":     strings.Join(ports, ","),
+		"ipList":    strings.Join(sg.IPList, ","),
+	}
+}
+
+// relate adds a relationship holding the properties from the node to the asset with the given ID and type, unless it already exists, and adds its ID to kept.
+// An existing relationship holding other properties, e.g. because the rule of a security group changed, is replaced.
+// The asset is looked up in the given namespace, or in the namespace of the node if none is given.
+// If the asset was not loaded yet, a placeholder with an empty body is created for it, which is filled in once the asset is loaded
+func relate(tx *graph.Tx, from graph.Node, namespace, id, label, relationship string, properties map[string]string, kept map[string]struct{}) error {
+	if namespace == "" {
+		namespace = from.GetNamespace()
+	}
+	to, err := tx.UpsertNodeInNamespace(namespace, id, label, []byte{})
+	if err != nil {
+		return err
+	}
+	for _, rel := range tx.Outgoing(from.GetID(), relationship) {
+		if rel.To != to.GetID() {
+			continue
 		}
-		for _, vpc := range vpcs {
-			if _, err := m.graph.AddRelationship(node.GetID(), vpc.GetID(), "part_of"); err != nil {
-				return err
-			}
+		if sameProperties(rel.Properties, properties) {
+			kept[rel.ID] = struct{}{}
+			return nil
 		}
+		if err := tx.DeleteRelationship(rel.ID); err != nil {
+			return err
+		}
+	}
+	rel, err := tx.AddRelationshipWithProperties(from.GetID(), to.GetID(), relationship, properties)
+	if err != nil {
+		return err
 	}
+	kept[rel.ID] = struct{}{}
 	return nil
 }
 
-// ListExposedVMs returns a list of VMs that accept connections from 0.0.0.0/0
-func (m *Manager) ListExposedVMs() []string {
-	rule := func(node graph.Node) bool {
-		sg := SecurityGroup{}
-		if err := json.Unmarshal(node.Body, &sg); err != nil {
-			// only printing the error, since an error here means there is no useful information to extract, but we still need to continue checking
-			// TODO: consider adding a check for invalid bodies?
-			log.Printf("error: unable to unmarshal security group %s; %s; this might indicate corrupt data \n", node.GetName(), err.Error())
+// dropStale removes the relationships starting from a loaded asset that are not in kept. All the relationships of an asset come from its data,
+// so the ones left from a previous load of the asset, that its data no longer has, are stale
+func dropStale(tx *graph.Tx, from graph.Node, kept map[string]struct{}) error {
+	for _, rel := range tx.Outgoing(from.GetID()) {
+		if _, ok := kept[rel.ID]; ok {
+			continue
 		}
-		for _, network := range sg.IPList {
-			if network == "0.0.0.0/0" && sg.Direction == "inbound" {
-				return true
-			}
+		if err := tx.DeleteRelationship(rel.ID); err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+// sameProperties returns true if both hold the same keys and values; nil and empty properties are the same
+func sameProperties(a, b map[string]string) bool {
+	if len(a) != len(b) {
 		return false
 	}
-	return m.findVMsBySecurityIssue(rule)
+	for k, v := range a {
+		if other, ok := b[k]; !ok || other != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ListExposedVMs returns the sorted references of the VMs that accept connections from 0.0.0.0/0
+func (m *Manager) ListExposedVMs(ctx context.Context, opts graph.PathOptions) ([]string, error) {
+	vms, err := m.ExposedVMs(ctx, opts)
+	return references(vms), err
+}
+
+// ExposedVMs returns the VMs that accept connections from 0.0.0.0/0, sorted by their reference
+func (m *Manager) ExposedVMs(ctx context.Context, opts graph.PathOptions) ([]graph.Node, error) {
+	return m.findVMsBySecurityIssue(ctx, opts,
+		graph.FilterNodesByField("direction", "inbound"),
+		graph.FilterNodesByField("ipList", "0.0.0.0/0"))
+}
+
+// ListHTTPPortVMs returns the sorted references of the VMs that have port 80 opened, either directly on the VM, or on a connected interface
+func (m *Manager) ListHTTPPortVMs(ctx context.Context, opts graph.PathOptions) ([]string, error) {
+	vms, err := m.findVMsBySecurityIssue(ctx, opts,
+		graph.FilterNodesByField("direction", "inbound"),
+		graph.FilterNodesByField("exposedPorts", 80))
+	return references(vms), err
+}
+
+// references returns the references of the nodes, which can be looked up again with Find
+func references(nodes []graph.Node) []string {
+	refs := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		refs = append(refs, node.Reference())
+	}
+	return refs
+}
+
+// ListConnections list all possible relationship chains between the 2 points.
+// If the search is stopped by one of the limits in the options, the chains found so far are returned along with a *graph.LimitError
+func (m *Manager) ListConnections(ctx context.Context, from, to string, opts graph.PathOptions) ([]*graph.ChainLink, error) {
+	grf := m.graph.Snapshot()
+	fromNode, err := m.uniqueNode(grf, from)
+	if err != nil {
+		return []*graph.ChainLink{}, err
+	}
+	toNode, err := m.uniqueNode(grf, to)
+	if err != nil {
+		return []*graph.ChainLink{}, err
+	}
+	chains, err := grf.ListConnectionsContext(ctx, fromNode, toNode, opts)
+	if err == nil {
+		err = storeErr(grf)
+	}
+	return chains, err
+}
+
+// ShortestConnection returns the relationship chain between the 2 points that passes through the least amount of assets.
+// If no chain is found within the depth limit in the options, a *graph.LimitError is returned; the path limit does not apply
+func (m *Manager) ShortestConnection(ctx context.Context, from, to string, opts graph.PathOptions) (*graph.ChainLink, error) {
+	grf := m.graph.Snapshot()
+	fromNode, err := m.uniqueNode(grf, from)
+	if err != nil {
+		return nil, err
+	}
+	toNode, err := m.uniqueNode(grf, to)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := grf.ShortestPath(ctx, fromNode, toNode, opts)
+	if err == nil {
+		err = storeErr(grf)
+	}
+	return chain, err
+}
+
+// Dependents returns the tree of assets that depend on the one with the given name, i.e.: all the assets that have a chain of relationships leading to it.
+// The direction in the options is ignored, since relationships are always followed backwards
+func (m *Manager) Dependents(ctx context.Context, name string, opts graph.PathOptions) (*graph.TreeNode, error) {
+	grf := m.graph.Snapshot()
+	node, err := m.uniqueNode(grf, name)
+	if err != nil {
+		return nil, err
+	}
+	opts.Direction = graph.Inbound
+	return grf.Traverse(ctx, node, opts)
+}
+
+// Neighbourhood returns a standalone graph with the assets that are at most the given number of relationships away from the one with the given name, and the relationships between them.
+// The asset the neighbourhood is built around is returned as well
+func (m *Manager) Neighbourhood(name string, hops int, direction graph.Direction) (*graph.Graph, graph.Node, error) {
+	grf := m.graph.Snapshot()
+	node, err := m.uniqueNode(grf, name)
+	if err != nil {
+		return nil, graph.Node{}, err
+	}
+	sub, err := grf.Neighbourhood(node.GetID(), hops, direction)
+	if err == nil {
+		err = storeErr(grf)
+	}
+	if err != nil {
+		return nil, graph.Node{}, err
+	}
+	return sub, node, nil
 }
 
-// ListHTTPPortVMs returns a list of VMs that have port 80 opened, either directly on the VM, or on a connected interface
-func (m *Manager) ListHTTPPortVMs() []string {
-	rule := func(node graph.Node) bool {
-		sg := SecurityGroup{}
-		if err := json.Unmarshal(node.Body, &sg); err != nil {
-			// only printing the error, since an error here means there is no useful information to extract, but we still need to continue checking
-			// TODO: consider adding a check for invalid bodies?
-			log.Printf("error: unable to unmarshal security group %s; %s; this might indicate corrupt data \n", node.GetName(), err.Error())
+// Cycles returns the cycles of relationships between assets, along with the groups of assets that reference each other, directly or through other assets.
+// When the manager is limited to some namespaces, only the cycles and groups that include assets from them are returned.
+// If the search is stopped by one of the limits in the options, the cycles found so far are returned along with a *graph.LimitError
+func (m *Manager) Cycles(ctx context.Context, opts graph.PathOptions) ([][]graph.Node, []*graph.ChainLink, error) {
+	grf := m.graph.Snapshot()
+	groups := [][]graph.Node{}
+	for _, component := range grf.StronglyConnectedComponents(opts.Labels...) {
+		// assets that are not part of a cycle form a group by themselves
+		if len(component) > 1 && m.anyInScope(component) {
+			groups = append(groups, component)
 		}
-		for _, port := range sg.ExposedPorts {
-			if port == 80 && sg.Direction == "inbound" {
-				return true
-			}
+	}
+	found, err := grf.Cycles(ctx, opts)
+	cycles := []*graph.ChainLink{}
+	for _, cycle := range found {
+		if m.anyInScope(cycle.Nodes()) {
+			cycles = append(cycles, cycle)
 		}
-		return false
 	}
-	return m.findVMsBySecurityIssue(rule)
+	if err == nil {
+		err = storeErr(grf)
+	}
+	return groups, cycles, err
+}
+
+// Find returns the assets a reference points to. The reference is first looked up as a name in the namespaces of the manager, since names can hold the namespace separator, e.g. ARNs.
+// If no asset has that name, and the reference is qualified with a namespace (namespace:name), the asset is looked up in that namespace instead
+func (m *Manager) Find(ref string) []graph.Node {
+	return m.find(m.graph, ref)
+}
+
+func (m *Manager) find(grf *graph.Graph, ref string) []graph.Node {
+	where := []graph.NodeFilter{graph.FilterNodesByName(ref)}
+	if len(m.namespaces) > 0 {
+		where = append(where, graph.FilterNodesByNamespace(m.namespaces...))
+	}
+	nodes := grf.ListNodes(where...)
+	if len(nodes) > 0 {
+		return nodes
+	}
+	namespace, name, qualified := graph.ParseReference(ref)
+	if !qualified {
+		return nodes
+	}
+	return grf.ListNodes(graph.FilterNodesByName(name), graph.FilterNodesByNamespace(namespace))
+}
+
+// uniqueNode returns the only asset in the graph the reference points to, looked up as by Find
+func (m *Manager) uniqueNode(grf *graph.Graph, ref string) (graph.Node, error) {
+	nodes := m.find(grf, ref)
+	if len(nodes) != 1 {
+		// an asset that could not be read is missing, rather than not unique
+		if err := storeErr(grf); err != nil {
+			return graph.Node{}, err
+		}
+		return graph.Node{}, fmt.Errorf("could not uniquely identify node %s; found %d elements", ref, len(nodes))
+	}
+	return nodes[0], nil
 }
 
-// ListConnections list all possible relationship chains between the 2 points
-func (m *Manager) ListConnections(from, to string) ([]string, error) {
-	fromNodes := m.graph.ListNodes(graph.FilterNodesByName(from))
-	if len(fromNodes) != 1 {
-		return []string{}, fmt.Errorf("could not uniquely identify node %s; found %d elements", from, len(fromNodes))
+// anyInScope returns true if any of the nodes is in one of the namespaces of the manager
+func (m *Manager) anyInScope(nodes []graph.Node) bool {
+	for _, node := range nodes {
+		if m.inScope(node) {
+			return true
+		}
 	}
-	toNodes := m.graph.ListNodes(graph.FilterNodesByName(to))
-	if len(fromNodes) != 1 {
-		return []string{}, fmt.Errorf("could not uniquely identify node %s; found %d elements", to, len(toNodes))
+	return false
+}
+
+// inScope returns true if the node is in one of the namespaces of the manager
+func (m *Manager) inScope(node graph.Node) bool {
+	if len(m.namespaces) == 0 {
+		return true
 	}
-	chains := m.graph.ListConnections(fromNodes[0], toNodes[0])
-	connections := make([]string, len(chains))
-	for i, v := range chains {
-		connections[i] = v.String()
+	for _, namespace := range m.namespaces {
+		if node.GetNamespace() == namespace {
+			return true
+		}
 	}
-	return connections, nil
+	return false
 }
 
-// findVMsBySecurityIssue searches for VMs that have connections to a SecurityGroup that is in violation of the given rule
-func (m *Manager) findVMsBySecurityIssue(rule graph.FilterNodes) []string {
-	exposedVMs := []string{}
-	// get security groups that habe port 80 opened
-	openedSecurityGroups := m.graph.ListNodes(
-		graph.FilterNodesByLabel(SecurityGroupType),
-		rule)
-	vms := m.graph.ListNodes(graph.FilterNodesByLabel(VirtualMacineType))
+// findVMsBySecurityIssue searches for VMs that have connections to a SecurityGroup that is in violation of all the given rules.
+// The rules should filter on indexed fields, so that the security groups are looked up in the index instead of decoding all of them.
+// The VMs are found by walking the relationships backwards from each security group, so the direction in the options is ignored, and the path limit caps the number of VMs returned
+func (m *Manager) findVMsBySecurityIssue(ctx context.Context, opts graph.PathOptions, rules ...graph.NodeFilter) ([]graph.Node, error) {
+	grf := m.graph.Snapshot()
+	exposedVMs := []graph.Node{}
+	// get security groups that are in violation of the rules
+	openedSecurityGroups := grf.ListNodes(append([]graph.NodeFilter{graph.FilterNodesByLabel(SecurityGroupType)}, rules...)...)
+	var limitErr error
 	for _, sg := range openedSecurityGroups {
+		vms, err := reachableFrom(ctx, grf, sg, VirtualMacineType, opts)
 		for _, vm := range vms {
-			if cons := m.graph.ListConnections(vm, sg); len(cons) > 0 {
-				exposedVMs = append(exposedVMs, vm.GetName())
+			// the security group can be in another namespace than the VMs using it, so only the VMs are checked against the namespaces
+			if !m.inScope(vm) {
+				continue
+			}
+			if opts.MaxPaths > 0 && len(exposedVMs) >= opts.MaxPaths {
+				sortByReference(exposedVMs)
+				return exposedVMs, &graph.LimitError{Limit: graph.LimitMaxPaths, Value: opts.MaxPaths}
+			}
+			exposedVMs = append(exposedVMs, vm)
+		}
+		var limit *graph.LimitError
+		switch {
+		case errors.As(err, &limit):
+			// keep checking the other security groups, the results are reported as incomplete at the end
+			limitErr = err
+		case err != nil:
+			return exposedVMs, err
+		}
+	}
+	if err := storeErr(grf); err != nil {
+		return exposedVMs, err
+	}
+	sortByReference(exposedVMs)
+	return exposedVMs, limitErr
+}
+
+func sortByReference(nodes []graph.Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Reference() < nodes[j].Reference()
+	})
+}
+
+// storeErr returns the error the store of the graph ran into, if any, since assets that could not be read are missing from the results
+func storeErr(grf *graph.Graph) error {
+	if err := grf.Err(); err != nil {
+		return fmt.Errorf("could not read assets; %w", err)
+	}
+	return nil
+}
+
+// reachableFrom walks the incoming relationships of the node and returns all the nodes with the given label that have a chain of relationships leading to it
+func reachableFrom(ctx context.Context, grf *graph.Graph, node graph.Node, label string, opts graph.PathOptions) ([]graph.Node, error) {
+	found := []graph.Node{}
+	visited := map[string]struct{}{node.GetID(): {}}
+	level := []string{node.GetID()}
+	for depth := 0; len(level) > 0; depth++ {
+		if err := ctx.Err(); err != nil {
+			return found, fmt.Errorf("search cancelled; %w", err)
+		}
+		next := []string{}
+		for _, current := range level {
+			for _, rel := range grf.Incoming(current, opts.Labels...) {
+				if _, ok := visited[rel.From]; ok {
+					continue
+				}
+				if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+					return found, &graph.LimitError{Limit: graph.LimitMaxDepth, Value: opts.MaxDepth}
+				}
+				visited[rel.From] = struct{}{}
+				next = append(next, rel.From)
+				from, err := grf.GetNodeByID(rel.From)
+				if err != nil {
+					continue
+				}
+				if from.GetLabel() == label {
+					found = append(found, from)
+				}
 			}
 		}
+		level = next
 	}
-	return exposedVMs
+	return found, nil
 }
@@ -1,8 +1,12 @@
 package assets_test
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -10,96 +14,494 @@ import (
 	"github.com/mimatache/cyscale/internal/graph"
 )
 
+// testData holds the contents of the inventory files in testdata
+type testData struct {
+	vpcs, securityGroups, interfaces, vms []byte
+}
+
+// readTestData reads the inventory files in testdata
+func readTestData(t *testing.T) testData {
+	t.Helper()
+	data := testData{}
+	files := map[string]*[]byte{
+		"testdata/VPC.json":              &data.vpcs,
+		"testdata/SecurityGroup.json":    &data.securityGroups,
+		"testdata/NetworkInterface.json": &data.interfaces,
+		"testdata/VM.json":               &data.vms,
+	}
+	for path, contents := range files {
+		var err error
+		*contents, err = os.ReadFile(path)
+		assert.NoError(t, err, "error reading files")
+	}
+	return data
+}
+
+// loadTestManager loads the inventory files in testdata into a new graph
+func loadTestManager(t *testing.T) (*graph.Graph, *assets.Manager) {
+	t.Helper()
+	data := readTestData(t)
+	grf := graph.New()
+	m, err := assets.NewManager(grf, data.vpcs, data.securityGroups, data.interfaces, data.vms)
+	assert.NoError(t, err)
+	return grf, m
+}
+
 func Test_NewManager(t *testing.T) {
-	intfContents, err := os.ReadFile("testdata/NetworkInterface.json")
-	assert.NoError(t, err, "error reading files")
+	grf, _ := loadTestManager(t)
+
+	assert.Equal(t, 11, len(grf.ListNodes()))
+	assert.Equal(t, 18, len(grf.ListRelationships()))
+}
+
+func Test_ExposedVMs(t *testing.T) {
+	_, m := loadTestManager(t)
+
+	vms, err := m.ListExposedVMs(context.Background(), graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(vms))
+	assert.Contains(t, vms, "VM_1")
+	assert.Contains(t, vms, "VM_2")
+}
+
+func Test_ListHTTPPortVMs(t *testing.T) {
+	_, m := loadTestManager(t)
+
+	vms, err := m.ListHTTPPortVMs(context.Background(), graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(vms))
+	assert.Contains(t, vms, "VM_1")
+}
+
+func Test_ListConnections(t *testing.T) {
+	_, m := loadTestManager(t)
+
+	chains, err := m.ListConnections(context.Background(), "VM_1", "vpc-06bcacc5531641a68", graph.PathOptions{})
+	assert.NoError(t, err)
+	cons := make([]string, len(chains))
+	for i, chain := range chains {
+		assert.Equal(t, "VM_1", chain.Nodes()[0].GetName())
+		assert.Equal(t, "vpc-06bcacc5531641a68", chain.Nodes()[chain.Len()].GetName())
+		cons[i] = chain.String()
+	}
+	assert.Equal(t, 6, len(cons))
+	assert.Contains(t, cons, "{Asset:VM_1}->{rel:VM_1-part_of-vpc-06bcacc5531641a68}->{Asset:vpc-06bcacc5531641a68}")
+	assert.Contains(t, cons, "{Asset:VM_1}->{rel:VM_1-part_of-sg-095531efae90566d5}->{Asset:sg-095531efae90566d5}->{rel:sg-095531efae90566d5-part_of-vpc-06bcacc5531641a68}->{Asset:vpc-06bcacc5531641a68}")
+	assert.Contains(t, cons, "{Asset:VM_1}->{rel:VM_1-using-eni-0c02d0e2602622897}->{Asset:eni-0c02d0e2602622897}->{rel:eni-0c02d0e2602622897-part_of-vpc-06bcacc5531641a68}->{Asset:vpc-06bcacc5531641a68}")
+	assert.Contains(t, cons, "{Asset:VM_1}->{rel:VM_1-using-eni-0c02d0e2602622897}->{Asset:eni-0c02d0e2602622897}->{rel:eni-0c02d0e2602622897-part_of-sg-095531efae90566d5}->{Asset:sg-095531efae90566d5}->{rel:sg-095531efae90566d5-part_of-vpc-06bcacc5531641a68}->{Asset:vpc-06bcacc5531641a68}")
+	assert.Contains(t, cons, "{Asset:VM_1}->{rel:VM_1-using-eni-0c1000541fb09e879}->{Asset:eni-0c1000541fb09e879}->{rel:eni-0c1000541fb09e879-part_of-vpc-06bcacc5531641a68}->{Asset:vpc-06bcacc5531641a68}")
+	assert.Contains(t, cons, "{Asset:VM_1}->{rel:VM_1-using-eni-0c1000541fb09e879}->{Asset:eni-0c1000541fb09e879}->{rel:eni-0c1000541fb09e879-part_of-sg-095531efae90566d5}->{Asset:sg-095531efae90566d5}->{rel:sg-095531efae90566d5-part_of-vpc-06bcacc5531641a68}->{Asset:vpc-06bcacc5531641a68}")
+}
+
+func Test_NewManager_LoadTwice(t *testing.T) {
+	grf, _ := loadTestManager(t)
+	data := readTestData(t)
+	_, err := assets.NewManager(grf, data.vpcs, data.securityGroups, data.interfaces, data.vms)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 11, len(grf.ListNodes()))
+	assert.Equal(t, 18, len(grf.ListRelationships()))
+}
+
+func Test_NewManager_RuleProperties(t *testing.T) {
+	grf, _ := loadTestManager(t)
+	rules := grf.ListRelationships(graph.FilterRelByLabel("part_of"), graph.FilterRelByProperty("direction", "inbound"))
+	assert.Len(t, rules, 2)
+	assert.Equal(t, "sg-095531efae90566d5", rules[0].FromName)
+	assert.Equal(t, map[string]string{"direction": "inbound", "ports": "80,443", "ipList": "0.0.0.0/0"}, rules[0].Properties)
+
+	// loading a changed rule replaces the relationship, instead of adding another one
+	_, err := assets.NewManager(grf, []byte("[]"), []byte(`[{"groupID":"sg-095531efae90566d5","vpcID":"vpc-06bcacc5531641a68","exposedPorts":[22],"direction":"inbound","ipList":["10.0.0.0/8"]}]`), []byte("[]"), []byte("[]"))
+	assert.NoError(t, err)
+	rules = grf.ListRelationships(graph.FilterRelByFrom(rules[0].From), graph.FilterRelByLabel("part_of"))
+	assert.Len(t, rules, 1)
+	assert.Equal(t, "22", rules[0].Properties["ports"])
+	assert.Equal(t, 18, len(grf.ListRelationships()))
+}
 
-	vmContents, err := os.ReadFile("testdata/VM.json")
-	assert.NoError(t, err, "error reading files")
+func Test_NewManager_Reload(t *testing.T) {
+	grf := graph.New()
+	vpcs := []byte(`[{"name": "VPC_1", "vpcID": "vpc-1", "peeredVpcs": [{"vpcID": "vpc-2"}]}, {"name": "VPC_2", "vpcID": "vpc-2"}]`)
+	vms := []byte(`[{"name": "VM_1", "vpcID": "vpc-1", "securityGroupIDs": ["sg-1"], "networkInterfaceIDs": ["eni-1"]}]`)
+	m, err := assets.NewManager(grf, vpcs, []byte("[]"), []byte("[]"), vms)
+	assert.NoError(t, err)
+	assert.Len(t, grf.ListRelationships(), 4)
+
+	// the VPC is no longer peered, and the VM no longer uses the interface
+	vpcs = []byte(`[{"name": "VPC_1", "vpcID": "vpc-1"}]`)
+	vms = []byte(`[{"name": "VM_1", "vpcID": "vpc-1", "securityGroupIDs": ["sg-1"]}]`)
+	assert.NoError(t, m.Load("", vpcs, []byte("[]"), []byte("[]"), vms))
 
-	vpcContents, err := os.ReadFile("testdata/VPC.json")
-	assert.NoError(t, err, "error reading files")
+	vpc, err := m.VirtualPrivateCloud(m.Find("vpc-1")[0])
+	assert.NoError(t, err)
+	assert.Empty(t, vpc.PeeredVpcs)
+	vm, err := m.VirtualMachine(m.Find("VM_1")[0])
+	assert.NoError(t, err)
+	assert.Empty(t, vm.NetworkInterfaceIDs)
+	assert.Empty(t, grf.ListRelationships(graph.FilterRelByLabel("peered_with")))
+	assert.Empty(t, grf.ListRelationships(graph.FilterRelByLabel("using")))
+	assert.Len(t, grf.ListRelationships(), 2)
+	// the assets that are no longer referenced are kept, as they might still be in the inventory
+	assert.Len(t, grf.ListNodes(), 5)
+}
 
-	sgContents, err := os.ReadFile("testdata/SecurityGroup.json")
-	assert.NoError(t, err, "error reading files")
+func Test_NewManager_FillsPlaceholders(t *testing.T) {
+	data := readTestData(t)
 
 	grf := graph.New()
-	_, err = assets.NewManager(grf, vpcContents, sgContents, intfContents, vmContents)
+	// the security groups are referenced before they are loaded
+	_, err := assets.NewManager(grf, data.vpcs, []byte("[]"), data.interfaces, data.vms)
 	assert.NoError(t, err)
+	sgs := grf.ListNodes(graph.FilterNodesByName("sg-095531efae90566d5"))
+	assert.Len(t, sgs, 1)
+	assert.Empty(t, sgs[0].Body)
 
+	m, err := assets.NewManager(grf, []byte("[]"), data.securityGroups, []byte("[]"), []byte("[]"))
+	assert.NoError(t, err)
+	sgs = grf.ListNodes(graph.FilterNodesByName("sg-095531efae90566d5"))
+	assert.Len(t, sgs, 1)
+	assert.NotEmpty(t, sgs[0].Body)
 	assert.Equal(t, 11, len(grf.ListNodes()))
 	assert.Equal(t, 18, len(grf.ListRelationships()))
+	vms, err := m.ListExposedVMs(context.Background(), graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"VM_1", "VM_2"}, vms)
 }
 
-func Test_ExposedVMs(t *testing.T) {
-	intfContents, err := os.ReadFile("testdata/NetworkInterface.json")
-	assert.NoError(t, err, "error reading files")
+func Test_ShortestConnection(t *testing.T) {
+	_, m := loadTestManager(t)
+
+	con, err := m.ShortestConnection(context.Background(), "VM_1", "vpc-06bcacc5531641a68", graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, con.Len())
+	assert.Equal(t, "{Asset:VM_1}->{rel:VM_1-part_of-vpc-06bcacc5531641a68}->{Asset:vpc-06bcacc5531641a68}", con.String())
+
+	con, err = m.ShortestConnection(context.Background(), "VM_2", "sg-0c1c60fcc9fddc6ff", graph.PathOptions{Labels: []string{"using", "part_of"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "{Asset:VM_2}->{rel:VM_2-using-eni-0f41ca71be3851834}->{Asset:eni-0f41ca71be3851834}->{rel:eni-0f41ca71be3851834-part_of-sg-0c1c60fcc9fddc6ff}->{Asset:sg-0c1c60fcc9fddc6ff}", con.String())
+
+	_, err = m.ShortestConnection(context.Background(), "VM_1", "sg-095531efae90566d5", graph.PathOptions{Labels: []string{"using"}})
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+
+	_, err = m.ShortestConnection(context.Background(), "VM_1", "missing", graph.PathOptions{})
+	assert.Error(t, err)
+}
+
+func Test_ShortestConnection_Limits(t *testing.T) {
+	_, m := loadTestManager(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	_, err := m.ShortestConnection(ctx, "VM_1", "vpc-06bcacc5531641a68", graph.PathOptions{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the interface is two relationships away from the security group
+	limitErr := &graph.LimitError{}
+	_, err = m.ShortestConnection(context.Background(), "VM_2", "sg-0c1c60fcc9fddc6ff", graph.PathOptions{MaxDepth: 1})
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, graph.LimitMaxDepth, limitErr.Limit)
+	con, err := m.ShortestConnection(context.Background(), "VM_2", "sg-0c1c60fcc9fddc6ff", graph.PathOptions{MaxDepth: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, con.Len())
+}
+
+func Test_ListConnections_Limits(t *testing.T) {
+	_, m := loadTestManager(t)
+
+	limitErr := &graph.LimitError{}
+	cons, err := m.ListConnections(context.Background(), "VM_1", "vpc-06bcacc5531641a68", graph.PathOptions{MaxPaths: 2})
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, graph.LimitMaxPaths, limitErr.Limit)
+	assert.Equal(t, 2, len(cons))
 
-	vmContents, err := os.ReadFile("testdata/VM.json")
-	assert.NoError(t, err, "error reading files")
+	cons, err = m.ListConnections(context.Background(), "VM_1", "vpc-06bcacc5531641a68", graph.PathOptions{MaxDepth: 2})
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, graph.LimitMaxDepth, limitErr.Limit)
+	assert.Equal(t, 4, len(cons))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = m.ListConnections(ctx, "VM_1", "vpc-06bcacc5531641a68", graph.PathOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
 
-	vpcContents, err := os.ReadFile("testdata/VPC.json")
-	assert.NoError(t, err, "error reading files")
+func Test_ExposedVMs_Limits(t *testing.T) {
+	data := readTestData(t)
 
-	sgContents, err := os.ReadFile("testdata/SecurityGroup.json")
-	assert.NoError(t, err, "error reading files")
+	// the VM is only exposed through its interface
+	data.vms = []byte(`[{"name":"VM_3","networkInterfaceIDs":["eni-0c02d0e2602622897"],"vpcID":"vpc-06bcacc5531641a68"}]`)
 
 	grf := graph.New()
-	m, err := assets.NewManager(grf, vpcContents, sgContents, intfContents, vmContents)
+	m, err := assets.NewManager(grf, data.vpcs, data.securityGroups, data.interfaces, data.vms)
 	assert.NoError(t, err)
 
-	vms := m.ListExposedVMs()
-	assert.Equal(t, 2, len(vms))
-	assert.Contains(t, vms, "VM_1")
-	assert.Contains(t, vms, "VM_2")
+	limitErr := &graph.LimitError{}
+	vms, err := m.ListExposedVMs(context.Background(), graph.PathOptions{MaxDepth: 1})
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, graph.LimitMaxDepth, limitErr.Limit)
+	assert.Empty(t, vms)
+
+	vms, err = m.ListExposedVMs(context.Background(), graph.PathOptions{MaxDepth: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"VM_3"}, vms)
+
+	_, m = loadTestManager(t)
+	vms, err = m.ListExposedVMs(context.Background(), graph.PathOptions{MaxPaths: 1})
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, graph.LimitMaxPaths, limitErr.Limit)
+	assert.Len(t, vms, 1)
+	vms, err = m.ListExposedVMs(context.Background(), graph.PathOptions{MaxPaths: 2})
+	assert.NoError(t, err)
+	assert.Len(t, vms, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = m.ListExposedVMs(ctx, graph.PathOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
 }
 
-func Test_ListHTTPPortVMs(t *testing.T) {
-	intfContents, err := os.ReadFile("testdata/NetworkInterface.json")
-	assert.NoError(t, err, "error reading files")
+func Test_Dependents(t *testing.T) {
+	_, m := loadTestManager(t)
+
+	tree, err := m.Dependents(context.Background(), "sg-095531efae90566d5", graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "sg-095531efae90566d5", tree.Node.GetName())
+	dependents := []string{}
+	for _, child := range tree.Children {
+		dependents = append(dependents, child.Node.GetName())
+		assert.Empty(t, child.Children)
+	}
+	assert.Equal(t, []string{"eni-0c02d0e2602622897", "eni-0c1000541fb09e879", "VM_1"}, dependents)
+
+	tree, err = m.Dependents(context.Background(), "vpc-0ab6a5a04e78280f5", graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, tree.Children, 4)
+
+	_, err = m.Dependents(context.Background(), "missing", graph.PathOptions{})
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = m.Dependents(ctx, "sg-095531efae90566d5", graph.PathOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func Test_Neighbourhood(t *testing.T) {
+	_, m := loadTestManager(t)
+
+	sub, centre, err := m.Neighbourhood("sg-095531efae90566d5", 1, graph.Inbound)
+	assert.NoError(t, err)
+	assert.Equal(t, "sg-095531efae90566d5", centre.GetName())
+	_, err = sub.GetNodeByID(centre.GetID())
+	assert.NoError(t, err)
+	names := []string{}
+	for _, node := range sub.ListNodes() {
+		names = append(names, node.GetName())
+	}
+	assert.ElementsMatch(t, []string{"sg-095531efae90566d5", "eni-0c02d0e2602622897", "eni-0c1000541fb09e879", "VM_1"}, names)
+	// the VM uses both interfaces, so those relationships are part of the neighbourhood as well
+	assert.Len(t, sub.ListRelationships(graph.FilterRelByLabel("using")), 2)
+	assert.Len(t, sub.ListRelationships(graph.FilterRelByLabel("part_of")), 3)
+
+	_, _, err = m.Neighbourhood("missing", 1, graph.Both)
+	assert.Error(t, err)
+}
+
+func Test_Neighbourhood_FailedReads(t *testing.T) {
+	data := readTestData(t)
+	store, err := graph.NewTempDiskStore(t.TempDir())
+	assert.NoError(t, err)
+	grf, err := graph.NewWithStore(store)
+	assert.NoError(t, err)
+	m, err := assets.NewManager(grf, data.vpcs, data.securityGroups, data.interfaces, data.vms)
+	assert.NoError(t, err)
+	assert.NoError(t, grf.Close())
 
-	vmContents, err := os.ReadFile("testdata/VM.json")
-	assert.NoError(t, err, "error reading files")
+	// the asset can not be read, which is reported instead of it not being found
+	_, _, err = m.Neighbourhood("sg-095531efae90566d5", 1, graph.Both)
+	assert.ErrorIs(t, err, graph.ErrStoreClosed)
+}
 
-	vpcContents, err := os.ReadFile("testdata/VPC.json")
-	assert.NoError(t, err, "error reading files")
+func Test_NewManager_Rollback(t *testing.T) {
+	data := readTestData(t)
 
-	sgContents, err := os.ReadFile("testdata/SecurityGroup.json")
-	assert.NoError(t, err, "error reading files")
+	grf := graph.New()
+	// the VMs are loaded last, so everything else was already added to the graph when they fail to load
+	_, err := assets.NewManager(grf, data.vpcs, data.securityGroups, data.interfaces, []byte(`[{"name": "VM_1"`))
+	assert.Error(t, err)
+	assert.Empty(t, grf.ListNodes())
+	assert.Empty(t, grf.ListRelationships())
+}
 
+func Test_NewManagerFromGraph(t *testing.T) {
 	grf := graph.New()
-	m, err := assets.NewManager(grf, vpcContents, sgContents, intfContents, vmContents)
+	grf.InsertNode("sg1", assets.SecurityGroupType, []byte(`{"groupID":"sg1","exposedPorts":[80],"direction":"inbound"}`))
+	m, err := assets.NewManagerFromGraph(grf)
 	assert.NoError(t, err)
 
-	vms := m.ListHTTPPortVMs()
-	assert.Equal(t, 1, len(vms))
-	assert.Contains(t, vms, "VM_1")
+	nodes := grf.ListNodes(graph.FilterNodesByName("sg1"))
+	assert.Len(t, nodes, 1)
+	sg, err := m.SecurityGroup(nodes[0])
+	assert.NoError(t, err)
+	assert.Equal(t, []int{80}, sg.ExposedPorts)
+	_, err = m.VirtualMachine(nodes[0])
+	assert.ErrorIs(t, err, graph.ErrBodyType)
+
+	// corrupt data is rejected when the assets are loaded, instead of being skipped when checking them
+	_, err = grf.UpsertNode("sg2", assets.SecurityGroupType, []byte(`{"exposedPorts":"80"}`))
+	assert.ErrorIs(t, err, graph.ErrInvalidBody)
+
+	corrupt := graph.New()
+	corrupt.InsertNode("sg1", assets.SecurityGroupType, []byte(`{"exposedPorts":"80"}`))
+	_, err = assets.NewManagerFromGraph(corrupt)
+	assert.ErrorIs(t, err, graph.ErrInvalidBody)
 }
 
-func Test_ListConnections(t *testing.T) {
-	intfContents, err := os.ReadFile("testdata/NetworkInterface.json")
-	assert.NoError(t, err, "error reading files")
+func Test_NewManager_EdgeKinds(t *testing.T) {
+	grf := graph.New()
+	_, err := assets.NewManager(grf, []byte(`[]`), []byte(`[]`), []byte(`[]`), []byte(`[]`))
+	assert.NoError(t, err)
 
-	vmContents, err := os.ReadFile("testdata/VM.json")
-	assert.NoError(t, err, "error reading files")
+	vm1, err := grf.UpsertNode("VM_1", assets.VirtualMacineType, []byte{})
+	assert.NoError(t, err)
+	vm2, err := grf.UpsertNode("VM_2", assets.VirtualMacineType, []byte{})
+	assert.NoError(t, err)
+	vpc, err := grf.UpsertNode("vpc1", assets.VpcType, []byte{})
+	assert.NoError(t, err)
 
-	vpcContents, err := os.ReadFile("testdata/VPC.json")
-	assert.NoError(t, err, "error reading files")
+	_, err = grf.AddRelationship(vm1.GetID(), vpc.GetID(), "part_of")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(vm1.GetID(), vm2.GetID(), "part_of")
+	assert.ErrorIs(t, err, graph.ErrRelationshipNotAllowed)
+	_, err = grf.AddRelationship(vpc.GetID(), vm1.GetID(), "part_of")
+	assert.ErrorIs(t, err, graph.ErrRelationshipNotAllowed)
+}
 
-	sgContents, err := os.ReadFile("testdata/SecurityGroup.json")
-	assert.NoError(t, err, "error reading files")
+func Test_Load_Namespaces(t *testing.T) {
+	data := readTestData(t)
 
 	grf := graph.New()
-	m, err := assets.NewManager(grf, vpcContents, sgContents, intfContents, vmContents)
+	m, err := assets.NewManagerFromGraph(grf)
 	assert.NoError(t, err)
+	// both inventories use the same IDs, which must not be merged
+	assert.NoError(t, m.Load("a", data.vpcs, data.securityGroups, data.interfaces, data.vms))
+	peered := []byte(`[{"name": "VPC_3", "vpcID": "vpc-3", "peeredVpcs": [{"namespace": "a", "vpcID": "vpc-06bcacc5531641a68"}]}]`)
+	assert.NoError(t, m.Load("b", peered, data.securityGroups, data.interfaces, data.vms))
+
+	assert.Equal(t, 23, len(grf.ListNodes()))
+	assert.Equal(t, 11, len(grf.ListNodes(graph.FilterNodesByNamespace("a"))))
+	assert.Equal(t, 12, len(grf.ListNodes(graph.FilterNodesByNamespace("b"))))
 
-	cons, err := m.ListConnections("VM_1", "vpc-06bcacc5531641a68")
+	vms, err := m.ListExposedVMs(context.Background(), graph.PathOptions{})
 	assert.NoError(t, err)
-	assert.Equal(t, 6, len(cons))
-	assert.Contains(t, cons, "{Asset:VM_1}->{rel:VM_1-part_of-vpc-06bcacc5531641a68}->{Asset:vpc-06bcacc5531641a68}")
-	assert.Contains(t, cons, "{Asset:VM_1}->{rel:VM_1-part_of-sg-095531efae90566d5}->{Asset:sg-095531efae90566d5}->{rel:sg-095531efae90566d5-part_of-vpc-06bcacc5531641a68}->{Asset:vpc-06bcacc5531641a68}")
-	assert.Contains(t, cons, "{Asset:VM_1}->{rel:VM_1-using-eni-0c02d0e2602622897}->{Asset:eni-0c02d0e2602622897}->{rel:eni-0c02d0e2602622897-part_of-vpc-06bcacc5531641a68}->{Asset:vpc-06bcacc5531641a68}")
-	assert.Contains(t, cons, "{Asset:VM_1}->{rel:VM_1-using-eni-0c02d0e2602622897}->{Asset:eni-0c02d0e2602622897}->{rel:eni-0c02d0e2602622897-part_of-sg-095531efae90566d5}->{Asset:sg-095531efae90566d5}->{rel:sg-095531efae90566d5-part_of-vpc-06bcacc5531641a68}->{Asset:vpc-06bcacc5531641a68}")
-	assert.Contains(t, cons, "{Asset:VM_1}->{rel:VM_1-using-eni-0c1000541fb09e879}->{Asset:eni-0c1000541fb09e879}->{rel:eni-0c1000541fb09e879-part_of-vpc-06bcacc5531641a68}->{Asset:vpc-06bcacc5531641a68}")
-	assert.Contains(t, cons, "{Asset:VM_1}->{rel:VM_1-using-eni-0c1000541fb09e879}->{Asset:eni-0c1000541fb09e879}->{rel:eni-0c1000541fb09e879-part_of-sg-095531efae90566d5}->{Asset:sg-095531efae90566d5}->{rel:sg-095531efae90566d5-part_of-vpc-06bcacc5531641a68}->{Asset:vpc-06bcacc5531641a68}")
+	assert.ElementsMatch(t, []string{"a:VM_1", "a:VM_2", "b:VM_1", "b:VM_2"}, vms)
+
+	vms, err = m.InNamespaces("b").ListExposedVMs(context.Background(), graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"b:VM_1", "b:VM_2"}, vms)
+
+	// the name is ambiguous unless it is qualified, or the manager is limited to one namespace
+	_, err = m.ShortestConnection(context.Background(), "b:vpc-3", "vpc-06bcacc5531641a68", graph.PathOptions{})
+	assert.Error(t, err)
+	chain, err := m.InNamespaces("a").ShortestConnection(context.Background(), "b:vpc-3", "vpc-06bcacc5531641a68", graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "{Asset:b:vpc-3}->{rel:vpc-3-peered_with-vpc-06bcacc5531641a68}->{Asset:a:vpc-06bcacc5531641a68}", chain.String())
+
+	assert.Error(t, m.Load("a:b", data.vpcs, data.securityGroups, data.interfaces, data.vms))
+
+	// exposed VMs are returned as nodes, so they can be told apart even though their names are the same
+	nodes, err := m.InNamespaces("b").ExposedVMs(context.Background(), graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+	for _, node := range nodes {
+		assert.Equal(t, "b", node.GetNamespace())
+		assert.Equal(t, []graph.Node{node}, m.Find(node.Reference()))
+	}
+
+	// the neighbourhood is built around the qualified asset only, though its name is in both namespaces
+	sub, centre, err := m.Neighbourhood("b:VM_1", 1, graph.Both)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", centre.GetNamespace())
+	assert.Len(t, sub.ListNodes(graph.FilterNodesByName("VM_1")), 1)
+}
+
+func Test_Load_ReferencesWithSeparator(t *testing.T) {
+	arn := "arn:aws:ec2:eu-west-1:123456789012:vpc/vpc-1"
+	vpcs := []byte(`[{"name": "VPC_1", "vpcID": "` + arn + `"}, {"name": "VPC_2", "vpcID": "vpc-2", "peeredVpcs": [{"vpcID": "` + arn + `"}]}]`)
+	m, err := assets.NewManagerFromGraph(graph.New())
+	assert.NoError(t, err)
+	assert.NoError(t, m.Load("a", vpcs, []byte(`[]`), []byte(`[]`), []byte(`[]`)))
+
+	// IDs holding the namespace separator are neither split when loading, nor when looking them up
+	assert.Len(t, m.Graph().ListNodes(), 2)
+	nodes := m.Find(arn)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "a", nodes[0].GetNamespace())
+	chain, err := m.ShortestConnection(context.Background(), "vpc-2", arn, graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []graph.Node{m.Find("vpc-2")[0], nodes[0]}, chain.Nodes())
+	assert.Equal(t, nodes, m.Find("a:"+arn))
+}
+
+func Test_NewManager_IndexedFields(t *testing.T) {
+	grf, _ := loadTestManager(t)
+
+	assert.ElementsMatch(t, []string{"direction", "ipList", "exposedPorts"}, grf.IndexedFields(assets.SecurityGroupType))
+	assert.Len(t, grf.ListNodes(graph.FilterNodesByLabel(assets.VirtualMacineType), graph.FilterNodesByField("vpcID", "vpc-06bcacc5531641a68")), 1)
+}
+
+func Test_NewManagerWithStore(t *testing.T) {
+	data := readTestData(t)
+
+	path := filepath.Join(t.TempDir(), "assets.store")
+	store, err := graph.OpenDiskStore(path)
+	assert.NoError(t, err)
+	m, err := assets.NewManagerWithStore(store)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Load("", data.vpcs, data.securityGroups, data.interfaces, data.vms))
+	assert.NoError(t, m.Graph().Close())
+
+	// the assets written to the store are found again after reopening it
+	store, err = graph.OpenDiskStore(path)
+	assert.NoError(t, err)
+	m, err = assets.NewManagerWithStore(store)
+	assert.NoError(t, err)
+	defer m.Graph().Close()
+	assert.Equal(t, 11, len(m.Graph().ListNodes()))
+	assert.Equal(t, 18, len(m.Graph().ListRelationships()))
+
+	vms, err := m.ListExposedVMs(context.Background(), graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"VM_1", "VM_2"}, vms)
+}
+
+func Test_ExposedVMs_ConcurrentLoad(t *testing.T) {
+	data := readTestData(t)
+
+	grf := graph.New()
+	m, err := assets.NewManagerFromGraph(grf)
+	assert.NoError(t, err)
+
+	inventories := 20
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < inventories; i++ {
+			assert.NoError(t, m.Load(fmt.Sprintf("ns%d", i), data.vpcs, data.securityGroups, data.interfaces, data.vms))
+		}
+	}()
+	// each inventory has two exposed VMs, and checks never see an inventory that is only partly loaded
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+		vms, err := m.ListExposedVMs(context.Background(), graph.PathOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(vms)%2, "exposed VMs %v", vms)
+	}
+	vms, err := m.ListExposedVMs(context.Background(), graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, vms, 2*inventories)
 }
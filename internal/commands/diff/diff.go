@@ -0,0 +1,142 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mimatache/cyscale/internal/commands/inventory"
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+var (
+	before string
+	after  string
+	format string
+)
+
+const (
+	textFormat = "text"
+	jsonFormat = "json"
+)
+
+// Diff creates the command used to find what changed between two inventories
+func Diff() *cobra.Command {
+	diffCommand := &cobra.Command{
+		Use:   "diff",
+		Short: "diff shows the assets and relationships that changed between two inventories. Example `diff --before scans/monday --after scans/tuesday`",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != textFormat && format != jsonFormat {
+				return fmt.Errorf("unknown output format %s", format)
+			}
+			beforeGraph, err := load(before)
+			if err != nil {
+				return err
+			}
+			defer beforeGraph.Close()
+			afterGraph, err := load(after)
+			if err != nil {
+				return err
+			}
+			defer afterGraph.Close()
+			changes, err := graph.Compare(beforeGraph, afterGraph)
+			if err != nil {
+				return fmt.Errorf("could not compare inventories; %w", err)
+			}
+			if format == jsonFormat {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(changes); err != nil {
+					return fmt.Errorf("could not encode output; %w", err)
+				}
+				return nil
+			}
+			printChanges(changes)
+			return nil
+		},
+	}
+
+	diffCommand.Flags().StringVar(&before, "before", "", "directory holding the inventory files, or graph snapshot, to compare against")
+	diffCommand.Flags().StringVar(&after, "after", "", "directory holding the inventory files, or graph snapshot, to compare")
+	diffCommand.Flags().StringVar(&format, "format", textFormat, "output format; one of: text, json")
+	_ = diffCommand.MarkFlagRequired("before")
+	_ = diffCommand.MarkFlagRequired("after")
+
+	return diffCommand
+}
+
+// load reads the inventory found at the path
+func load(path string) (*graph.Graph, error) {
+	source, err := inventory.SourceFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	grf, err := source.Graph()
+	if err != nil {
+		return nil, fmt.Errorf("could not load inventory %s; %w", path, err)
+	}
+	return grf, nil
+}
+
+func printChanges(changes *graph.Diff) {
+	if changes.Empty() {
+		fmt.Println("There are no changes")
+		return
+	}
+	printSection("Added assets:", nodes(changes.AddedNodes))
+	printSection("Removed assets:", nodes(changes.RemovedNodes))
+	if len(changes.ModifiedNodes) > 0 {
+		fmt.Println("Modified assets:")
+		for _, node := range changes.ModifiedNodes {
+			fmt.Printf("\t• %s\n", node.NodeKey)
+			for _, field := range node.Fields {
+				fmt.Printf("\t\t%s: %s -> %s\n", fieldName(field.Field), value(field.Before), value(field.After))
+			}
+		}
+	}
+	printSection("Added relationships:", relationships(changes.AddedRelationships))
+	printSection("Removed relationships:", relationships(changes.RemovedRelationships))
+}
+
+// printSection prints the title followed by the items, unless there are none
+func printSection(title string, items []fmt.Stringer) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Println(title)
+	for _, item := range items {
+		fmt.Printf("\t• %s\n", item)
+	}
+}
+
+func nodes(keys []graph.NodeKey) []fmt.Stringer {
+	items := make([]fmt.Stringer, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, key)
+	}
+	return items
+}
+
+func relationships(keys []graph.RelationshipKey) []fmt.Stringer {
+	items := make([]fmt.Stringer, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, key)
+	}
+	return items
+}
+
+func fieldName(field string) string {
+	if field == "" {
+		return "body"
+	}
+	return field
+}
+
+func value(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return "<none>"
+	}
+	return string(raw)
+}
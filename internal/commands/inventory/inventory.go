@@ -0,0 +1,144 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+var (
+	source     Source
+	output     string
+	format     string
+	components int
+)
+
+const (
+	textFormat = "text"
+	jsonFormat = "json"
+)
+
+// Inventory groups the commands used to work with the asset inventory itself, rather than checking it
+func Inventory() *cobra.Command {
+	inventoryCommand := &cobra.Command{
+		Use:   "inventory",
+		Short: "inventory is used to work with the asset inventory",
+	}
+
+	source.AddFlags(inventoryCommand)
+
+	snapshotCommand.Flags().StringVarP(&output, "output", "o", "inventory.snapshot", "path of the file the snapshot is written to")
+
+	statsCommand.Flags().StringVar(&format, "format", textFormat, "output format; one of: text, json")
+	statsCommand.Flags().IntVar(&components, "components", 5, "number of connected components shown, largest first; 0 shows all of them")
+
+	inventoryCommand.AddCommand(
+		snapshotCommand,
+		statsCommand,
+	)
+
+	return inventoryCommand
+}
+
+var snapshotCommand = &cobra.Command{
+	Use:   "snapshot",
+	Short: "snapshot builds the asset graph from the inventory files and saves it to a file that can be used with `verify --snapshot`",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		grf, err := source.Graph()
+		if err != nil {
+			return fmt.Errorf("could not load assets; %w", err)
+		}
+		defer grf.Close()
+		if err := grf.SaveSnapshot(output); err != nil {
+			return err
+		}
+		fmt.Printf("Snapshot written to %s\n", output)
+		return nil
+	},
+}
+
+var statsCommand = &cobra.Command{
+	Use:   "stats",
+	Short: "stats shows how many assets and relationships of each type the inventory has, and the assets that might point to gaps in it",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if format != textFormat && format != jsonFormat {
+			return fmt.Errorf("unknown output format %s", format)
+		}
+		grf, err := source.Graph()
+		if err != nil {
+			return fmt.Errorf("could not load assets; %w", err)
+		}
+		defer grf.Close()
+		stats, err := grf.Stats()
+		if err != nil {
+			return err
+		}
+		if components > 0 && len(stats.Components) > components {
+			stats.Components = stats.Components[:components]
+		}
+		if format == jsonFormat {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(stats); err != nil {
+				return fmt.Errorf("could not encode output; %w", err)
+			}
+			return nil
+		}
+		printStats(stats)
+		return nil
+	},
+}
+
+// printStats writes the statistics of the inventory to stdout in a human readable form
+func printStats(stats *graph.Stats) {
+	fmt.Printf("Assets: %d\n", stats.Nodes)
+	printCounts(stats.NodesByLabel)
+	fmt.Printf("Relationships: %d\n", stats.Relationships)
+	printCounts(stats.RelationshipsByLabel)
+
+	fmt.Println("Assets by number of relationships:")
+	degrees := make([]int, 0, len(stats.Degrees))
+	for degree := range stats.Degrees {
+		degrees = append(degrees, degree)
+	}
+	sort.Ints(degrees)
+	for _, degree := range degrees {
+		fmt.Printf("\t• %d: %d\n", degree, stats.Degrees[degree])
+	}
+
+	fmt.Printf("Assets without relationships: %d\n", len(stats.Orphans))
+	for _, key := range stats.Orphans {
+		fmt.Printf("\t• %s\n", key)
+	}
+	fmt.Printf("Assets referenced, but missing from the inventory: %d\n", len(stats.Placeholders))
+	for _, key := range stats.Placeholders {
+		fmt.Printf("\t• %s\n", key)
+	}
+
+	fmt.Println("Largest groups of connected assets:")
+	for _, component := range stats.Components {
+		names := make([]string, 0, len(component))
+		for _, key := range component {
+			names = append(names, key.String())
+		}
+		fmt.Printf("\t• %d assets: %s\n", len(component), strings.Join(names, ", "))
+	}
+}
+
+// printCounts writes the counts sorted by label
+func printCounts(counts map[string]int) {
+	labels := make([]string, 0, len(counts))
+	for label := range counts {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		fmt.Printf("\t• %s: %d\n", label, counts[label])
+	}
+}
@@ -0,0 +1,185 @@
+package inventory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mimatache/cyscale/assets"
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+// Source describes where the asset inventory is read from. It is either a set of json files, one for each asset type, a previously saved graph snapshot,
+// or several directories holding the json files, each loaded in its own namespace
+type Source struct {
+	Interfaces string
+	VMs        string
+	SGs        string
+	VPCs       string
+	Snapshot   string
+	// Inventories are given as namespace=directory
+	Inventories []string
+	// Store is where the graph is kept while the inventory is checked: in memory, or in a temporary file in StoreDir
+	Store    string
+	StoreDir string
+}
+
+// Stores the graph can be kept in
+const (
+	MemoryStore = "memory"
+	DiskStore   = "disk"
+)
+
+// Names of the inventory files, as found in a directory holding a full inventory
+const (
+	InterfacesFile = "NetworkInterface.json"
+	VMsFile        = "VM.json"
+	SGsFile        = "SecurityGroup.json"
+	VPCsFile       = "VPC.json"
+)
+
+// SourceFromPath returns the source of an inventory found at the given path, which is either a directory holding the inventory files, or a graph snapshot
+func SourceFromPath(path string) (Source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Source{}, fmt.Errorf("could not find inventory %s; %w", path, err)
+	}
+	if !info.IsDir() {
+		return Source{Snapshot: path}, nil
+	}
+	return Source{
+		Interfaces: filepath.Join(path, InterfacesFile),
+		VMs:        filepath.Join(path, VMsFile),
+		SGs:        filepath.Join(path, SGsFile),
+		VPCs:       filepath.Join(path, VPCsFile),
+	}, nil
+}
+
+// AddFlags registers the flags pointing to the inventory files on the given command
+func (s *Source) AddFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&s.Interfaces, "interfaces", "data/NetworkInterface.json", "path to file containing network interfaces to verify")
+	cmd.PersistentFlags().StringVar(&s.VMs, "virtual-machines", "data/VM.json", "path to file containing VMs to verify")
+	cmd.PersistentFlags().StringVar(&s.SGs, "security-groups", "data/SecurityGroup.json", "path to file containing security groups to verify")
+	cmd.PersistentFlags().StringVar(&s.VPCs, "virtual-private-cloud", "data/VPC.json", "path to file containing VPCs to verify")
+	cmd.PersistentFlags().StringSliceVar(&s.Inventories, "inventory", nil, "namespace=directory holding the inventory files of an account or region; can be repeated to load several inventories instead of the inventory files")
+	cmd.PersistentFlags().StringVar(&s.Store, "store", MemoryStore, fmt.Sprintf("where the graph is kept while checking the inventory; one of %s, %s (keeps the asset data in a temporary file, but the IDs and indexes in memory)", MemoryStore, DiskStore))
+	cmd.PersistentFlags().StringVar(&s.StoreDir, "store-dir", "", "directory of the temporary file used by the disk store; defaults to the directory for temporary files of the system")
+}
+
+// AddSnapshotFlag registers the flag used to read the inventory from a graph snapshot instead of the inventory files
+func (s *Source) AddSnapshotFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&s.Snapshot, "snapshot", "", "path to a graph snapshot to use instead of the inventory files")
+}
+
+// Manager loads the inventory and returns an asset manager on top of it. The graph of the manager must be closed once it is no longer used, to release its store
+func (s *Source) Manager() (*assets.Manager, error) {
+	if s.Snapshot != "" {
+		grf, err := s.loadSnapshot()
+		if err != nil {
+			return nil, err
+		}
+		m, err := assets.NewManagerFromGraph(grf)
+		if err != nil {
+			grf.Close()
+			return nil, err
+		}
+		return m, nil
+	}
+	_, m, err := s.load()
+	return m, err
+}
+
+// Graph loads the inventory and returns the graph containing all the assets. The graph must be closed once it is no longer used, to release its store
+func (s *Source) Graph() (*graph.Graph, error) {
+	if s.Snapshot != "" {
+		return s.loadSnapshot()
+	}
+	grf, _, err := s.load()
+	return grf, err
+}
+
+// newStore creates the store selected with the store flag
+func (s *Source) newStore() (graph.Store, error) {
+	switch s.Store {
+	case "", MemoryStore:
+		return graph.NewMemoryStore(), nil
+	case DiskStore:
+		return graph.NewTempDiskStore(s.StoreDir)
+	default:
+		return nil, fmt.Errorf("unknown store %s; expected one of %s, %s", s.Store, MemoryStore, DiskStore)
+	}
+}
+
+func (s *Source) loadSnapshot() (*graph.Graph, error) {
+	store, err := s.newStore()
+	if err != nil {
+		return nil, err
+	}
+	grf, err := graph.LoadSnapshotWithStore(s.Snapshot, store)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	return grf, nil
+}
+
+func (s *Source) load() (*graph.Graph, *assets.Manager, error) {
+	store, err := s.newStore()
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := assets.NewManagerWithStore(store)
+	if err != nil {
+		store.Close()
+		return nil, nil, err
+	}
+	grf := m.Graph()
+	if err := s.loadInventories(m); err != nil {
+		grf.Close()
+		return nil, nil, err
+	}
+	return grf, m, nil
+}
+
+// loadInventories loads the inventory files, or each of the inventories in its own namespace
+func (s *Source) loadInventories(m *assets.Manager) error {
+	if len(s.Inventories) == 0 {
+		return loadFiles(m, "", s.Interfaces, s.VMs, s.SGs, s.VPCs)
+	}
+	for _, inventory := range s.Inventories {
+		parts := strings.SplitN(inventory, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("inventory %s should be given as namespace=directory", inventory)
+		}
+		namespace, dir := parts[0], parts[1]
+		err := loadFiles(m, namespace, filepath.Join(dir, InterfacesFile), filepath.Join(dir, VMsFile), filepath.Join(dir, SGsFile), filepath.Join(dir, VPCsFile))
+		if err != nil {
+			return fmt.Errorf("could not load inventory %s; %w", namespace, err)
+		}
+	}
+	return nil
+}
+
+// loadFiles reads the inventory files and loads them in the given namespace
+func loadFiles(m *assets.Manager, namespace, interfaces, vms, sgs, vpcs string) error {
+	interfaceContents, err := os.ReadFile(interfaces)
+	if err != nil {
+		return fmt.Errorf("could not read interface file %s; %w", interfaces, err)
+	}
+	vmContents, err := os.ReadFile(vms)
+	if err != nil {
+		return fmt.Errorf("could not read vm file %s; %w", vms, err)
+	}
+	sgContents, err := os.ReadFile(sgs)
+	if err != nil {
+		return fmt.Errorf("could not read sg file %s; %w", sgs, err)
+	}
+	vpcContents, err := os.ReadFile(vpcs)
+	if err != nil {
+		return fmt.Errorf("could not read vpc file %s; %w", vpcs, err)
+	}
+	return m.Load(namespace, vpcContents, sgContents, interfaceContents, vmContents)
+}
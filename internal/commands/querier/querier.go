@@ -0,0 +1,109 @@
+package querier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mimatache/cyscale/internal/commands/inventory"
+	"github.com/mimatache/cyscale/internal/query"
+)
+
+var (
+	source  inventory.Source
+	format  string
+	timeout time.Duration
+)
+
+const (
+	tableFormat = "table"
+	jsonFormat  = "json"
+)
+
+// Query creates the command used to ask ad-hoc questions about the assets
+func Query() *cobra.Command {
+	queryCommand := &cobra.Command{
+		Use:   "query <expression>",
+		Short: "query finds the assets matching a pattern. Example `query 'MATCH (vm:vm)-[:using]->(i:interface) WHERE i.vpcID = \"vpc-1\" RETURN vm.name, i'`",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != tableFormat && format != jsonFormat {
+				return fmt.Errorf("unknown output format %s", format)
+			}
+			q, err := query.Parse(args[0])
+			if err != nil {
+				return err
+			}
+			grf, err := source.Graph()
+			if err != nil {
+				return fmt.Errorf("could not load assets; %w", err)
+			}
+			defer grf.Close()
+			ctx := context.Background()
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			result, err := q.Run(ctx, grf)
+			if err != nil {
+				return err
+			}
+			if format == jsonFormat {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(result.Records()); err != nil {
+					return fmt.Errorf("could not encode output; %w", err)
+				}
+				return nil
+			}
+			return printTable(result)
+		},
+	}
+
+	source.AddFlags(queryCommand)
+	source.AddSnapshotFlag(queryCommand)
+	queryCommand.Flags().StringVar(&format, "format", tableFormat, "output format; one of: table, json")
+	queryCommand.Flags().DurationVar(&timeout, "timeout", 0, "maximum duration of the query (e.g.: 30s); 0 means no limit")
+
+	return queryCommand
+}
+
+// printTable writes the result to stdout, one row per line, with the columns aligned
+func printTable(result *query.Result) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(result.Columns, "\t")))
+	for _, row := range result.Rows {
+		cells := make([]string, 0, len(row))
+		for _, value := range row {
+			cells = append(cells, cell(value))
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("could not write output; %w", err)
+	}
+	fmt.Printf("%d rows\n", len(result.Rows))
+	return nil
+}
+
+// cell formats a value for the table: strings as they are, missing values as empty cells, and anything else as json
+func cell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
@@ -6,6 +6,10 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/mimatache/cyscale/internal/commands/about"
+	"github.com/mimatache/cyscale/internal/commands/diff"
+	"github.com/mimatache/cyscale/internal/commands/inventory"
+	"github.com/mimatache/cyscale/internal/commands/querier"
+	"github.com/mimatache/cyscale/internal/commands/topology"
 	"github.com/mimatache/cyscale/internal/commands/verifier"
 )
 
@@ -24,6 +28,10 @@ This should not be treated as an exhaustive security scan of your cloud environm
 		about.Version,
 		about.License,
 		verifier.Verify(),
+		inventory.Inventory(),
+		topology.Graph(),
+		diff.Diff(),
+		querier.Query(),
 	)
 
 	return rootCommand
@@ -0,0 +1,225 @@
+package topology
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mimatache/cyscale/assets"
+	"github.com/mimatache/cyscale/internal/commands/inventory"
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+var (
+	source           inventory.Source
+	format           string
+	output           string
+	labels           []string
+	highlight        []string
+	highlightExposed bool
+	highlightPath    []string
+	hops             int
+	direction        string
+	neighbourFormat  string
+)
+
+const (
+	dotFormat     = "dot"
+	mermaidFormat = "mermaid"
+	graphMLFormat = "graphml"
+	textFormat    = "text"
+)
+
+// directions maps the values of the direction flag to the way relationships are followed
+var directions = map[string]graph.Direction{
+	"out":  graph.Outbound,
+	"in":   graph.Inbound,
+	"both": graph.Both,
+}
+
+// Graph groups the commands used to look at the asset graph as a whole
+func Graph() *cobra.Command {
+	graphCommand := &cobra.Command{
+		Use:   "graph",
+		Short: "graph is used to inspect the graph of assets",
+	}
+
+	source.AddFlags(graphCommand)
+	source.AddSnapshotFlag(graphCommand)
+
+	exportCommand.Flags().StringVar(&format, "format", dotFormat, "output format; one of: dot, mermaid, graphml")
+	exportCommand.Flags().StringVarP(&output, "output", "o", "", "path of the file the graph is written to; defaults to stdout")
+	exportCommand.Flags().StringSliceVar(&labels, "labels", nil, "only export assets of these types (e.g.: vm,securityGroup)")
+	exportCommand.Flags().StringSliceVar(&highlight, "highlight", nil, "names of the assets to highlight; assets from another inventory can be given as namespace:name")
+	exportCommand.Flags().BoolVar(&highlightExposed, "highlight-exposed", false, "highlight the VMs that are exposed to the internet")
+	exportCommand.Flags().StringSliceVar(&highlightPath, "highlight-path", nil, "highlight the connections between two assets (e.g.: --highlight-path VM_1,vpc1)")
+
+	neighbourhoodCommand.Flags().IntVar(&hops, "hops", 1, "maximum number of relationships between the asset and its neighbours")
+	neighbourhoodCommand.Flags().StringVar(&direction, "direction", "both", "which relationships are followed from the asset; one of: out, in, both")
+	neighbourhoodCommand.Flags().StringVar(&neighbourFormat, "format", textFormat, "output format; one of: text, dot, mermaid, graphml")
+	neighbourhoodCommand.Flags().StringVarP(&output, "output", "o", "", "path of the file the neighbourhood is written to; defaults to stdout")
+
+	graphCommand.AddCommand(
+		exportCommand,
+		neighbourhoodCommand,
+	)
+
+	return graphCommand
+}
+
+var exportCommand = &cobra.Command{
+	Use:   "export",
+	Short: "export writes the graph of assets in a format that can be rendered by other tools",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		write, err := exporter(format)
+		if err != nil {
+			return err
+		}
+		m, err := source.Manager()
+		if err != nil {
+			return fmt.Errorf("could not load assets; %w", err)
+		}
+		defer m.Graph().Close()
+		opts, err := exportOptions(m)
+		if err != nil {
+			return err
+		}
+		return writeOutput(func(w io.Writer) error {
+			return write(m.Graph(), w, opts)
+		})
+	},
+}
+
+var neighbourhoodCommand = &cobra.Command{
+	Use:   "neighbourhood",
+	Short: "neighbourhood shows the assets that are at most a number of relationships away from an asset. Example `neighbourhood VM_1 --hops 2`",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("neighbourhood requires one argument to function correctly")
+		}
+		dir, ok := directions[direction]
+		if !ok {
+			return fmt.Errorf("unknown direction %s", direction)
+		}
+		var write func(g *graph.Graph, w io.Writer, opts graph.ExportOptions) error
+		if neighbourFormat != textFormat {
+			var err error
+			if write, err = exporter(neighbourFormat); err != nil {
+				return err
+			}
+		}
+		m, err := source.Manager()
+		if err != nil {
+			return fmt.Errorf("could not load assets; %w", err)
+		}
+		defer m.Graph().Close()
+		sub, centre, err := m.Neighbourhood(args[0], hops, dir)
+		if err != nil {
+			return err
+		}
+		if write == nil {
+			return writeOutput(func(w io.Writer) error {
+				return printNeighbourhood(sub, w)
+			})
+		}
+		// the asset the neighbourhood was built around is highlighted
+		opts := graph.ExportOptions{Styles: assets.NodeStyles, Highlight: []string{centre.GetID()}}
+		return writeOutput(func(w io.Writer) error {
+			return write(sub, w, opts)
+		})
+	},
+}
+
+// printNeighbourhood lists the assets in the graph grouped by type, followed by the relationships between them
+func printNeighbourhood(g *graph.Graph, w io.Writer) error {
+	// both lists are sorted, so the nodes come grouped by type
+	nodes := g.ListNodes()
+	rels := g.ListRelationships()
+	var b strings.Builder
+	label := ""
+	for _, node := range nodes {
+		if node.GetLabel() != label {
+			label = node.GetLabel()
+			fmt.Fprintf(&b, "%s:\n", label)
+		}
+		fmt.Fprintf(&b, "\t• %s\n", node.GetName())
+	}
+	if len(rels) > 0 {
+		fmt.Fprintln(&b, "relationships:")
+	}
+	for _, rel := range rels {
+		fmt.Fprintf(&b, "\t• %s -%s-> %s\n", rel.FromName, rel.Label, rel.ToName)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// exporter returns the function that writes the graph in the given format
+func exporter(format string) (func(g *graph.Graph, w io.Writer, opts graph.ExportOptions) error, error) {
+	switch format {
+	case dotFormat:
+		return (*graph.Graph).WriteDOT, nil
+	case mermaidFormat:
+		return (*graph.Graph).WriteMermaid, nil
+	case graphMLFormat:
+		return (*graph.Graph).WriteGraphML, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %s", format)
+	}
+}
+
+// exportOptions builds the export options from the flags, finding the assets that need to be highlighted
+func exportOptions(m *assets.Manager) (graph.ExportOptions, error) {
+	opts := graph.ExportOptions{
+		Labels: labels,
+		Styles: assets.NodeStyles,
+	}
+	for _, ref := range highlight {
+		for _, node := range m.Find(ref) {
+			opts.Highlight = append(opts.Highlight, node.GetID())
+		}
+	}
+	if err := m.Graph().Err(); err != nil {
+		return opts, fmt.Errorf("could not find the assets to highlight; %w", err)
+	}
+	if highlightExposed {
+		vms, err := m.ExposedVMs(context.Background(), graph.PathOptions{})
+		if err != nil {
+			return opts, fmt.Errorf("could not find exposed VMs; %w", err)
+		}
+		for _, vm := range vms {
+			opts.Highlight = append(opts.Highlight, vm.GetID())
+		}
+	}
+	if len(highlightPath) > 0 {
+		if len(highlightPath) != 2 {
+			return opts, fmt.Errorf("--highlight-path requires two asset names, got %s", strings.Join(highlightPath, ","))
+		}
+		chains, err := m.ListConnections(context.Background(), highlightPath[0], highlightPath[1], graph.PathOptions{})
+		if err != nil {
+			return opts, fmt.Errorf("could not find connections; %w", err)
+		}
+		opts.HighlightPaths = chains
+	}
+	return opts, nil
+}
+
+// writeOutput writes either to the file given through the output flag, or to stdout
+func writeOutput(write func(w io.Writer) error) error {
+	if output == "" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("could not create file %s; %w", output, err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -1,20 +1,37 @@
 package verifier
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/mimatache/cyscale/assets"
+	"github.com/mimatache/cyscale/internal/commands/inventory"
 	"github.com/mimatache/cyscale/internal/graph"
 )
 
 var (
-	interfaces string
-	vms        string
-	sgs        string
-	vpcs       string
+	source           inventory.Source
+	shortest         bool
+	connectionLabels []string
+	cycleLabels      []string
+	maxDepth         int
+	maxPaths         int
+	timeout          time.Duration
+	format           string
+	namespaces       []string
+)
+
+const (
+	textFormat    = "text"
+	jsonFormat    = "json"
+	mermaidFormat = "mermaid"
 )
 
 func Verify() *cobra.Command {
@@ -23,29 +40,88 @@ func Verify() *cobra.Command {
 		Short: "verify is used to check conditions",
 	}
 
-	verifyCommand.PersistentFlags().StringVar(&interfaces, "interfaces", "data/NetworkInterface.json", "path to file containing network interfaces to verify")
-	verifyCommand.PersistentFlags().StringVar(&vms, "virtual-machines", "data/VM.json", "path to file containing VMs to verify")
-	verifyCommand.PersistentFlags().StringVar(&sgs, "security-groups", "data/SecurityGroup.json", "path to file containing security groups to verify")
-	verifyCommand.PersistentFlags().StringVar(&vpcs, "virtual-private-cloud", "data/VPC.json", "path to file containing VPCs to verify")
+	source.AddFlags(verifyCommand)
+	source.AddSnapshotFlag(verifyCommand)
+	verifyCommand.PersistentFlags().IntVar(&maxDepth, "max-depth", 0, "maximum number of relationships followed from an asset; 0 means no limit")
+	verifyCommand.PersistentFlags().IntVar(&maxPaths, "max-paths", 0, "maximum number of connections, cycles or VMs listed; 0 means no limit")
+	verifyCommand.PersistentFlags().DurationVar(&timeout, "timeout", 0, "maximum duration of a check (e.g.: 30s); 0 means no limit")
+	verifyCommand.PersistentFlags().StringSliceVar(&namespaces, "namespace", nil, "only check the assets in these namespaces (i.e.: inventories); assets in other namespaces can be referenced as namespace:name")
+
+	listConnections.Flags().BoolVar(&shortest, "shortest", false, "only show the connection passing through the least amount of assets")
+	listConnections.Flags().StringVar(&format, "format", textFormat, "output format; one of: text, json, mermaid")
+	listConnections.Flags().StringSliceVar(&connectionLabels, "relationship-labels", nil, "only follow relationships having one of these labels")
+	cycles.Flags().StringSliceVar(&cycleLabels, "relationship-labels", nil, "only follow relationships having one of these labels")
 
 	verifyCommand.AddCommand(
 		exposedVMCommand,
 		vmUsingHTTPPort,
 		listConnections,
+		dependents,
+		cycles,
 	)
 
 	return verifyCommand
 }
 
+// manager loads the assets, and limits the checks to the namespaces given as flags
+func manager() (*assets.Manager, error) {
+	m, err := source.Manager()
+	if err != nil {
+		return nil, fmt.Errorf("could not load assets; %w", err)
+	}
+	if len(namespaces) > 0 {
+		return m.InNamespaces(namespaces...), nil
+	}
+	return m, nil
+}
+
+// searchOptions builds the context and the options for searching through the assets, following the given relationship labels, based on the limits given as flags
+func searchOptions(labels []string) (context.Context, context.CancelFunc, graph.PathOptions) {
+	opts := graph.PathOptions{
+		Labels:   labels,
+		MaxDepth: maxDepth,
+		MaxPaths: maxPaths,
+	}
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		return ctx, cancel, opts
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return ctx, cancel, opts
+}
+
+// limitReached separates errors caused by reaching a search limit, which only mean that the results are incomplete, from actual failures
+func limitReached(err error) (*graph.LimitError, error) {
+	limitErr := &graph.LimitError{}
+	if errors.As(err, &limitErr) {
+		return limitErr, nil
+	}
+	return nil, err
+}
+
+func printWarning(limitErr *graph.LimitError) {
+	if limitErr != nil {
+		fmt.Printf("Warning: %s\n", limitErr)
+	}
+}
+
 var exposedVMCommand = &cobra.Command{
 	Use:   "exposed-vms",
 	Short: "exposed-vms shows which VMs are exposed to the internet (i.e.: allow connections from 0.0.0.0/0)",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		m, err := getAssetManager(interfaces, vms, sgs, vpcs)
+		m, err := manager()
 		if err != nil {
-			return fmt.Errorf("could not load assets; %w", err)
+			return err
 		}
-		vms := m.ListExposedVMs()
+		defer m.Graph().Close()
+		ctx, cancel, opts := searchOptions(nil)
+		defer cancel()
+		vms, err := m.ListExposedVMs(ctx, opts)
+		limitErr, err := limitReached(err)
+		if err != nil {
+			return err
+		}
+		defer printWarning(limitErr)
 		if len(vms) == 0 {
 			fmt.Println("There are no exposed VMs")
 			return nil
@@ -62,11 +138,19 @@ var vmUsingHTTPPort = &cobra.Command{
 	Use:   "vms-using-http-port",
 	Short: "vms-using-http-port shows which VMs are using the HTTP port, either directly or through an interface",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		m, err := getAssetManager(interfaces, vms, sgs, vpcs)
+		m, err := manager()
 		if err != nil {
-			return fmt.Errorf("could not load assets; %w", err)
+			return err
 		}
-		vms := m.ListHTTPPortVMs()
+		defer m.Graph().Close()
+		ctx, cancel, opts := searchOptions(nil)
+		defer cancel()
+		vms, err := m.ListHTTPPortVMs(ctx, opts)
+		limitErr, err := limitReached(err)
+		if err != nil {
+			return err
+		}
+		defer printWarning(limitErr)
 		if len(vms) == 0 {
 			fmt.Println("There are no VMs using the HTTP port")
 			return nil
@@ -86,14 +170,60 @@ var listConnections = &cobra.Command{
 		if len(args) != 2 {
 			return fmt.Errorf("list-connections requires two arguments to function correctly")
 		}
-		m, err := getAssetManager(interfaces, vms, sgs, vpcs)
+		if format != textFormat && format != jsonFormat && format != mermaidFormat {
+			return fmt.Errorf("unknown output format %s", format)
+		}
+		m, err := manager()
 		if err != nil {
-			return fmt.Errorf("could not load assets; %w", err)
+			return err
+		}
+		defer m.Graph().Close()
+		ctx, cancel, opts := searchOptions(connectionLabels)
+		defer cancel()
+		if shortest {
+			connection, err := m.ShortestConnection(ctx, args[0], args[1], opts)
+			limitErr, err := limitReached(err)
+			if limitErr != nil || errors.Is(err, graph.ErrNotFound) {
+				if format == jsonFormat {
+					if limitErr != nil {
+						fmt.Fprintf(os.Stderr, "Warning: %s\n", limitErr)
+					}
+					return printJSON(nil)
+				}
+				defer printWarning(limitErr)
+				fmt.Printf("There are no connections between %s and %s\n", args[0], args[1])
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			switch format {
+			case jsonFormat:
+				return printJSON(connection)
+			case mermaidFormat:
+				return graph.WriteMermaidPaths(os.Stdout, []*graph.ChainLink{connection}, assets.NodeStyles)
+			}
+			fmt.Printf("Shortest connection between %s and %s\n", args[0], args[1])
+			fmt.Printf("\t• %s\n", connection)
+			return nil
 		}
-		connections, err := m.ListConnections(args[0], args[1])
+		connections, err := m.ListConnections(ctx, args[0], args[1], opts)
+		limitErr, err := limitReached(err)
 		if err != nil {
 			return err
 		}
+		switch format {
+		case jsonFormat, mermaidFormat:
+			// the warning is kept out of stdout so that the output can be used as it is
+			if limitErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", limitErr)
+			}
+			if format == jsonFormat {
+				return printJSON(connections)
+			}
+			return graph.WriteMermaidPaths(os.Stdout, connections, assets.NodeStyles)
+		}
+		defer printWarning(limitErr)
 		if len(connections) == 0 {
 			fmt.Printf("There are no connections between %s and %s\n", args[0], args[1])
 			return nil
@@ -106,23 +236,93 @@ var listConnections = &cobra.Command{
 	},
 }
 
-func getAssetManager(interfaces, vms, sgs, vpcs string) (*assets.Manager, error) {
-	graph := graph.New()
-	interfaceContents, err := os.ReadFile(interfaces)
-	if err != nil {
-		return nil, fmt.Errorf("could not read interface file %s; %w", interfaces, err)
-	}
-	vmContents, err := os.ReadFile(vms)
-	if err != nil {
-		return nil, fmt.Errorf("could not read vm file %s; %w", vms, err)
-	}
-	sgContents, err := os.ReadFile(sgs)
-	if err != nil {
-		return nil, fmt.Errorf("could not read sg file %s; %w", sgs, err)
+var dependents = &cobra.Command{
+	Use:   "dependents",
+	Short: "dependents shows which assets depend on an asset, directly or through other assets, grouped by type. Example `dependents sg1`",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("dependents requires one argument to function correctly")
+		}
+		m, err := manager()
+		if err != nil {
+			return err
+		}
+		defer m.Graph().Close()
+		ctx, cancel, opts := searchOptions(nil)
+		defer cancel()
+		tree, err := m.Dependents(ctx, args[0], opts)
+		limitErr, err := limitReached(err)
+		if err != nil {
+			return err
+		}
+		defer printWarning(limitErr)
+		if len(tree.Children) == 0 {
+			fmt.Printf("There are no assets depending on %s\n", args[0])
+			return nil
+		}
+		fmt.Printf("Assets depending on %s (%s)\n", tree.Node.GetName(), tree.Node.GetLabel())
+		printDependents(tree, 1)
+		return nil
+	},
+}
+
+// printDependents prints the children of the tree node grouped by their type, with each level of the tree indented further
+func printDependents(tree *graph.TreeNode, depth int) {
+	indent := strings.Repeat("\t", depth)
+	label := ""
+	for _, child := range tree.Children {
+		// children are sorted by label, so a new group starts whenever the label changes
+		if child.Node.GetLabel() != label {
+			label = child.Node.GetLabel()
+			fmt.Printf("%s%s:\n", indent, label)
+		}
+		fmt.Printf("%s\t• %s (%s)\n", indent, child.Node.GetName(), child.Via.Label)
+		printDependents(child, depth+2)
 	}
-	vpcContents, err := os.ReadFile(vpcs)
-	if err != nil {
-		return nil, fmt.Errorf("could not read vpc file %s; %w", vpcs, err)
+}
+
+var cycles = &cobra.Command{
+	Use:   "cycles",
+	Short: "cycles shows the groups of assets that reference each other, and the cycles of relationships between them",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := manager()
+		if err != nil {
+			return err
+		}
+		defer m.Graph().Close()
+		ctx, cancel, opts := searchOptions(cycleLabels)
+		defer cancel()
+		groups, found, err := m.Cycles(ctx, opts)
+		limitErr, err := limitReached(err)
+		if err != nil {
+			return err
+		}
+		defer printWarning(limitErr)
+		if len(found) == 0 {
+			fmt.Println("There are no cycles")
+			return nil
+		}
+		fmt.Println("Assets referencing each other:")
+		for _, group := range groups {
+			names := make([]string, 0, len(group))
+			for _, node := range group {
+				names = append(names, node.Reference())
+			}
+			fmt.Printf("\t• %s\n", strings.Join(names, ", "))
+		}
+		fmt.Println("Cycles:")
+		for _, cycle := range found {
+			fmt.Printf("\t• %s\n", cycle)
+		}
+		return nil
+	},
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("could not encode output; %w", err)
 	}
-	return assets.NewManager(graph, vpcContents, sgContents, interfaceContents, vmContents)
+	return nil
 }
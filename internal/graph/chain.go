@@ -0,0 +1,123 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ChainLink is one step of a path through the graph: a node and the relationship leading to the next link. The last link of a path only has a node
+type ChainLink struct {
+	node Node
+	rel  Relationship
+	next *ChainLink
+}
+
+// Node returns the node of this link
+func (c *ChainLink) Node() Node {
+	return c.node
+}
+
+// Relationship returns the relationship connecting the node of this link to the next one. It points either way, depending on the direction the path followed it in.
+// The second value is false for the last link of the chain
+func (c *ChainLink) Relationship() (Relationship, bool) {
+	return c.rel, c.next != nil
+}
+
+// Next returns the following link in the chain, or nil if this is the last one. It can be used to iterate over the chain:
+//
+//	for link := chain; link != nil; link = link.Next() {}
+func (c *ChainLink) Next() *ChainLink {
+	return c.next
+}
+
+// Nodes returns all the nodes in the chain, in order, starting with this link
+func (c *ChainLink) Nodes() []Node {
+	nodes := []Node{}
+	for link := c; link != nil; link = link.next {
+		nodes = append(nodes, link.node)
+	}
+	return nodes
+}
+
+// Relationships returns all the relationships in the chain, in order. The relationship at index i connects the node at index i and the one at index i+1,
+// pointing from the former to the latter unless the path followed it backwards, as for Inbound paths
+func (c *ChainLink) Relationships() []Relationship {
+	rels := []Relationship{}
+	for link := c; link != nil && link.next != nil; link = link.next {
+		rels = append(rels, link.rel)
+	}
+	return rels
+}
+
+// Len returns the number of relationships in the chain, which is 0 for a nil chain
+func (c *ChainLink) Len() int {
+	length := 0
+	for link := c; link != nil && link.next != nil; link = link.next {
+		length++
+	}
+	return length
+}
+
+func (c *ChainLink) String() string {
+	var sb strings.Builder
+	if c.node.GetID() != "" {
+		sb.WriteString(c.node.String())
+	}
+	if c.rel.ID != "" {
+		sb.WriteString(fmt.Sprintf("->%s", c.rel.String()))
+	}
+	if c.next != nil {
+		sb.WriteString(fmt.Sprintf("->%s", c.next.String()))
+	}
+	return sb.String()
+}
+
+const (
+	nodeElement         = "node"
+	relationshipElement = "relationship"
+)
+
+// pathElement is the json representation of either a node or a relationship in a chain
+type pathElement struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id"`
+	Label     string          `json:"label"`
+	Namespace string          `json:"namespace,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	From      string          `json:"from,omitempty"`
+	To        string          `json:"to,omitempty"`
+	Body      json.RawMessage `json:"body,omitempty"`
+	// Properties are only set for relationships
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// MarshalJSON encodes the chain as an ordered list of elements, alternating between nodes and the relationships connecting them
+func (c *ChainLink) MarshalJSON() ([]byte, error) {
+	elements := []pathElement{}
+	for link := c; link != nil; link = link.next {
+		element := pathElement{
+			Type:      nodeElement,
+			ID:        link.node.id,
+			Namespace: link.node.namespace,
+			Label:     link.node.label,
+			Name:      link.node.name,
+		}
+		if json.Valid(link.node.Body) {
+			element.Body = link.node.Body
+		}
+		elements = append(elements, element)
+		if link.next == nil {
+			break
+		}
+		elements = append(elements, pathElement{
+			Type:       relationshipElement,
+			ID:         link.rel.ID,
+			Label:      link.rel.Label,
+			From:       link.rel.From,
+			To:         link.rel.To,
+			Properties: link.rel.Properties,
+		})
+	}
+	return json.Marshal(elements)
+}
@@ -0,0 +1,100 @@
+package graph_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_ChainLink_Accessors(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	rel1, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	rel2, err := grf.AddRelationship(aNode.GetID(), dNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	chain, err := grf.ShortestPath(context.Background(), bNode, dNode, graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, chain.Len())
+	assert.Equal(t, []graph.Node{bNode, aNode, dNode}, chain.Nodes())
+	assert.Equal(t, []graph.Relationship{rel1, rel2}, chain.Relationships())
+
+	names := []string{}
+	for link := chain; link != nil; link = link.Next() {
+		names = append(names, link.Node().GetName())
+		rel, ok := link.Relationship()
+		if link.Next() == nil {
+			assert.False(t, ok)
+			continue
+		}
+		assert.True(t, ok)
+		assert.Equal(t, link.Node().GetID(), rel.From)
+	}
+	assert.Equal(t, []string{bobita, azor, smaug}, names)
+}
+
+func Test_ChainLink_SingleNode(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	chain, err := grf.ShortestPath(context.Background(), bNode, bNode, graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, chain.Len())
+	assert.Equal(t, []graph.Node{bNode}, chain.Nodes())
+	assert.Empty(t, chain.Relationships())
+
+	var empty *graph.ChainLink
+	assert.Equal(t, 0, empty.Len())
+	assert.Empty(t, empty.Nodes())
+	assert.Empty(t, empty.Relationships())
+}
+
+func Test_ChainLink_Inbound(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	rel, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	// the relationship is followed backwards, so it points from the second node of the chain to the first one
+	chain, err := grf.ShortestPath(context.Background(), aNode, bNode, graph.PathOptions{Direction: graph.Inbound})
+	assert.NoError(t, err)
+	assert.Equal(t, []graph.Node{aNode, bNode}, chain.Nodes())
+	assert.Equal(t, []graph.Relationship{rel}, chain.Relationships())
+}
+
+func Test_ChainLink_MarshalJSON(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, []byte{})
+	rel, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	chain, err := grf.ShortestPath(context.Background(), bNode, aNode, graph.PathOptions{})
+	assert.NoError(t, err)
+	data, err := json.Marshal(chain)
+	assert.NoError(t, err)
+
+	elements := []map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(data, &elements))
+	assert.Len(t, elements, 3)
+	assert.Equal(t, "node", elements[0]["type"])
+	assert.Equal(t, bNode.GetID(), elements[0]["id"])
+	assert.Equal(t, bobita, elements[0]["name"])
+	assert.Equal(t, puppyType, elements[0]["label"])
+	assert.Equal(t, map[string]interface{}{"name": "Bobita", "power": float64(500)}, elements[0]["body"])
+	assert.Equal(t, "relationship", elements[1]["type"])
+	assert.Equal(t, rel.ID, elements[1]["id"])
+	assert.Equal(t, "friends", elements[1]["label"])
+	assert.Equal(t, bNode.GetID(), elements[1]["from"])
+	assert.Equal(t, aNode.GetID(), elements[1]["to"])
+	assert.Equal(t, "node", elements[2]["type"])
+	assert.Equal(t, azor, elements[2]["name"])
+	assert.NotContains(t, elements[2], "body")
+}
@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+var (
+	ErrInvalidBody = errors.New("the body does not match the type registered for the label")
+	ErrBodyType    = errors.New("the body can not be decoded into the given type")
+)
+
+// RegisterBodyType sets the Go type of the bodies of the nodes having the label. v is a value of that type, or a pointer to one.
+// From then on, InsertNode and UpsertNode reject json bodies that can not be decoded into the type or that hold fields it does not have, and DecodeBody only accepts a pointer to it.
+// Empty bodies are always accepted. If any of the nodes already in the graph has an invalid body, the type is not registered and ErrInvalidBody is returned
+func (g *Graph) RegisterBodyType(label string, v interface{}) error {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return fmt.Errorf("%w; nil given for label '%s'", ErrBodyType, label)
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	g.Lock()
+	defer g.Unlock()
+	for _, id := range g.store.Lookup(labelIndexName, label) {
+		node, _ := g.store.GetNode(id)
+		if err := validateBody(t, node); err != nil {
+			return err
+		}
+	}
+	if g.bodyTypes == nil {
+		g.bodyTypes = map[string]reflect.Type{}
+	}
+	g.bodyTypes[label] = t
+	return nil
+}
+
+// DecodeBody decodes the json body of the node into v. If a type was registered for the label of the node, v must be a pointer to it.
+// An empty body, as the one of a node created before its data was known, leaves v unchanged
+func (g *Graph) DecodeBody(node Node, v interface{}) error {
+	g.RLock()
+	t, ok := g.bodyTypes[node.label]
+	g.RUnlock()
+	if ok && reflect.TypeOf(v) != reflect.PtrTo(t) {
+		return fmt.Errorf("%w; expected *%s for label '%s', got %T", ErrBodyType, t, node.label, v)
+	}
+	if len(node.Body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(node.Body, v); err != nil {
+		return fmt.Errorf("could not decode body of node '%s'; %w", node.name, err)
+	}
+	return nil
+}
+
+// checkBody validates the body of the node against the type registered for its label, if any. The caller must hold the read lock
+func (g *Graph) checkBody(node Node) error {
+	t, ok := g.bodyTypes[node.label]
+	if !ok {
+		return nil
+	}
+	return validateBody(t, node)
+}
+
+// validateBody decodes the body into a value of the type, rejecting fields the type does not have and anything following the json value
+func validateBody(t reflect.Type, node Node) error {
+	if len(node.Body) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(node.Body))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(reflect.New(t).Interface())
+	if _, next := decoder.Token(); err == nil && next != io.EOF {
+		err = errors.New("unexpected data after the json value")
+	}
+	if err != nil {
+		return fmt.Errorf("%w; node '%s' with label '%s'; %s", ErrInvalidBody, node.name, node.label, err.Error())
+	}
+	return nil
+}
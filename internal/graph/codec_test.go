@@ -0,0 +1,89 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_Graph_RegisterBodyType(t *testing.T) {
+	grf := graph.New()
+	assert.NoError(t, grf.RegisterBodyType(puppyType, puppy{}))
+
+	node, err := grf.UpsertNode(bobita, puppyType, bobitaBody)
+	assert.NoError(t, err)
+	_, err = grf.UpsertNode(azor, puppyType, []byte(`{"power":"lots"}`))
+	assert.ErrorIs(t, err, graph.ErrInvalidBody)
+	assert.Empty(t, grf.ListNodes(graph.FilterNodesByName(azor)))
+
+	// an update that makes the body invalid leaves the node unchanged
+	_, err = grf.UpsertNode(bobita, puppyType, []byte(`{"power":"lots"}`))
+	assert.ErrorIs(t, err, graph.ErrInvalidBody)
+	stored, err := grf.GetNodeByID(node.GetID())
+	assert.NoError(t, err)
+	assert.Equal(t, bobitaBody, stored.Body)
+
+	// empty bodies and labels without a registered type are always accepted
+	_, err = grf.UpsertNode(azor, puppyType, []byte{})
+	assert.NoError(t, err)
+	_, err = grf.UpsertNode(smaug, dragonType, []byte(`not json`))
+	assert.NoError(t, err)
+}
+
+func Test_Graph_RegisterBodyType_Insert(t *testing.T) {
+	grf := graph.New()
+	assert.NoError(t, grf.RegisterBodyType(puppyType, puppy{}))
+
+	_, err := grf.InsertNode(bobita, puppyType, bobitaBody)
+	assert.NoError(t, err)
+	_, err = grf.InsertNode(azor, puppyType, []byte(`{"power":"lots"}`))
+	assert.ErrorIs(t, err, graph.ErrInvalidBody)
+	// fields the type does not have, and data following the body, are rejected as well
+	_, err = grf.InsertNode(azor, puppyType, []byte(`{"power":457,"canFly":true}`))
+	assert.ErrorIs(t, err, graph.ErrInvalidBody)
+	_, err = grf.InsertNode(azor, puppyType, []byte(`{"power":457} {}`))
+	assert.ErrorIs(t, err, graph.ErrInvalidBody)
+	_, err = grf.UpsertNode(bobita, puppyType, []byte(`{"canFly":true}`))
+	assert.ErrorIs(t, err, graph.ErrInvalidBody)
+	assert.Len(t, grf.ListNodes(), 1)
+
+	err = grf.Batch(func(tx *graph.Tx) error {
+		_, err := tx.InsertNode(azor, puppyType, []byte(`{"power":"lots"}`))
+		return err
+	})
+	assert.ErrorIs(t, err, graph.ErrInvalidBody)
+	assert.Len(t, grf.ListNodes(), 1)
+}
+
+func Test_Graph_RegisterBodyType_ExistingNodes(t *testing.T) {
+	grf := graph.New()
+	grf.InsertNode(bobita, puppyType, []byte(`{"power":"lots"}`))
+
+	assert.ErrorIs(t, grf.RegisterBodyType(puppyType, &puppy{}), graph.ErrInvalidBody)
+	// the type was not registered, so other invalid bodies are still accepted
+	_, err := grf.UpsertNode(azor, puppyType, []byte(`{"power":"lots"}`))
+	assert.NoError(t, err)
+}
+
+func Test_Graph_DecodeBody(t *testing.T) {
+	grf := graph.New()
+	assert.NoError(t, grf.RegisterBodyType(puppyType, &puppy{}))
+	node, err := grf.UpsertNode(bobita, puppyType, bobitaBody)
+	assert.NoError(t, err)
+
+	decoded := puppy{}
+	assert.NoError(t, grf.DecodeBody(node, &decoded))
+	assert.Equal(t, puppy{Name: bobita, Power: 500}, decoded)
+
+	wrongType := map[string]interface{}{}
+	assert.ErrorIs(t, grf.DecodeBody(node, &wrongType), graph.ErrBodyType)
+	assert.ErrorIs(t, grf.DecodeBody(node, decoded), graph.ErrBodyType)
+
+	placeholder, err := grf.UpsertNode(azor, puppyType, []byte{})
+	assert.NoError(t, err)
+	decoded = puppy{}
+	assert.NoError(t, grf.DecodeBody(placeholder, &decoded))
+	assert.Equal(t, puppy{}, decoded)
+}
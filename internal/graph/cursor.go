@@ -0,0 +1,177 @@
+package graph
+
+import (
+	"sort"
+)
+
+// Cursor goes through the nodes matching a set of filters a page at a time, in the order of ListNodes. It reads from a view of the graph taken when it is created,
+// so the pages do not skip or repeat nodes while the graph changes. The ID, label, name and namespace of every node that can match are held to sort them,
+// but the bodies are only read for the nodes of the current page.
+// A cursor is not safe for concurrent use
+type Cursor struct {
+	view     *Graph
+	where    []NodeFilter
+	pageSize int
+	// ids holds the sorted IDs of the nodes that can match the filters, and next the position of the first one not checked yet
+	ids  []string
+	next int
+	page []Node
+}
+
+// Cursor returns a cursor over the nodes matching all the where clauses, which are used as by ListNodes. A page size of 0 or less puts all the nodes in a single page.
+// The nodes are sorted using the indexes, without reading them, so the cursor does not read more than a page of nodes ahead
+func (g *Graph) Cursor(pageSize int, where ...NodeFilter) *Cursor {
+	view := g.Snapshot()
+	view.RLock()
+	defer view.RUnlock()
+	ids, indexed := view.candidates(where)
+	if !indexed {
+		ids = nil
+	}
+	keys := view.nodeKeys(ids)
+	if !indexed {
+		ids = make([]string, 0, len(keys))
+		for id := range keys {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return lessNode(keys[ids[i]], keys[ids[j]])
+	})
+	return &Cursor{
+		view:     view,
+		where:    where,
+		pageSize: pageSize,
+		ids:      ids,
+	}
+}
+
+// Next reads the next page of nodes, and returns false once there are no more nodes
+func (c *Cursor) Next() bool {
+	c.view.RLock()
+	defer c.view.RUnlock()
+	c.page = []Node{}
+	for c.next < len(c.ids) && (c.pageSize <= 0 || len(c.page) < c.pageSize) {
+		node, ok := c.view.store.GetNode(c.ids[c.next])
+		c.next++
+		if ok && c.view.matchesAll(node, c.where) {
+			c.page = append(c.page, node)
+		}
+	}
+	return len(c.page) > 0
+}
+
+// Nodes returns the page of nodes read by the last call to Next
+func (c *Cursor) Nodes() []Node {
+	return c.page
+}
+
+// Err returns the error the store ran into while reading the nodes, if any
+func (c *Cursor) Err() error {
+	return c.view.Err()
+}
+
+// nodeKeys returns the nodes with the given IDs, or all the nodes if ids is nil, holding only their ID, label, name and namespace, as found in the indexes.
+// The caller must hold the read lock
+func (g *Graph) nodeKeys(ids []string) map[string]Node {
+	keys := make(map[string]Node, len(ids))
+	for _, id := range ids {
+		keys[id] = Node{id: id}
+	}
+	all := ids == nil
+	for _, label := range g.store.Keys(labelIndexName) {
+		for _, id := range g.store.Lookup(labelIndexName, label) {
+			if key, ok := keys[id]; ok || all {
+				key.id, key.label = id, label
+				keys[id] = key
+			}
+		}
+	}
+	for _, name := range g.store.Keys(nameIndexName) {
+		for _, id := range g.store.Lookup(nameIndexName, name) {
+			if key, ok := keys[id]; ok {
+				key.name = name
+				keys[id] = key
+			}
+		}
+	}
+	for _, namespace := range g.store.Keys(namespaceIndexName) {
+		for _, id := range g.store.Lookup(namespaceIndexName, namespace) {
+			if key, ok := keys[id]; ok {
+				key.namespace = namespace
+				keys[id] = key
+			}
+		}
+	}
+	return keys
+}
+
+// RelationshipCursor goes through the relationships matching a set of filters a page at a time, in the order of ListRelationships.
+// As with Cursor, it reads from a view of the graph taken when it is created. A cursor is not safe for concurrent use
+type RelationshipCursor struct {
+	view     *Graph
+	filters  []FilterRelationship
+	pageSize int
+	// ids holds the sorted IDs of all the relationships, and next the position of the first one not checked yet
+	ids  []string
+	next int
+	page []Relationship
+}
+
+// RelationshipCursor returns a cursor over the relationships matching all the filters. A page size of 0 or less puts all the relationships in a single page.
+// The relationships are all read once to sort them, and the ID of each is held to go through them in that order; only the relationships of the current page are held in full
+func (g *Graph) RelationshipCursor(pageSize int, filters ...FilterRelationship) *RelationshipCursor {
+	view := g.Snapshot()
+	view.RLock()
+	defer view.RUnlock()
+	keys := make([]Relationship, 0, view.store.CountRelationships())
+	view.store.ForEachRelationship(func(rel Relationship) bool {
+		keys = append(keys, Relationship{ID: rel.ID, Label: rel.Label, FromName: rel.FromName, ToName: rel.ToName})
+		return true
+	})
+	sortRelationships(keys)
+	ids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, key.ID)
+	}
+	return &RelationshipCursor{
+		view:     view,
+		filters:  filters,
+		pageSize: pageSize,
+		ids:      ids,
+	}
+}
+
+// Next reads the next page of relationships, and returns false once there are no more relationships
+func (c *RelationshipCursor) Next() bool {
+	c.view.RLock()
+	defer c.view.RUnlock()
+	c.page = []Relationship{}
+	for c.next < len(c.ids) && (c.pageSize <= 0 || len(c.page) < c.pageSize) {
+		rel, ok := c.view.store.GetRelationship(c.ids[c.next])
+		c.next++
+		if ok && matchesRelationship(rel, c.filters) {
+			c.page = append(c.page, rel)
+		}
+	}
+	return len(c.page) > 0
+}
+
+// Relationships returns the page of relationships read by the last call to Next
+func (c *RelationshipCursor) Relationships() []Relationship {
+	return c.page
+}
+
+// Err returns the error the store ran into while reading the relationships, if any
+func (c *RelationshipCursor) Err() error {
+	return c.view.Err()
+}
+
+func matchesRelationship(rel Relationship, filters []FilterRelationship) bool {
+	for _, clause := range filters {
+		if ok := clause(rel); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package graph_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_Graph_ListNodes_Sorted(t *testing.T) {
+	grf := graph.New()
+	grf.InsertNode(smaug, dragonType, smaugBody)
+	grf.InsertNode(bobita, puppyType, bobitaBody)
+	grf.InsertNode(azor, puppyType, azorBody)
+	_, err := grf.UpsertNodeInNamespace("b", azor, puppyType, azorBody)
+	assert.NoError(t, err)
+	_, err = grf.UpsertNodeInNamespace("a", azor, puppyType, azorBody)
+	assert.NoError(t, err)
+
+	references := []string{}
+	for _, node := range grf.ListNodes() {
+		references = append(references, node.Reference())
+	}
+	assert.Equal(t, []string{smaug, azor, "a:" + azor, "b:" + azor, bobita}, references)
+}
+
+func Test_Graph_ListRelationships_Sorted(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	rel1, err := grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	rel2, err := grf.AddRelationship(bNode.GetID(), dNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	rel3, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	rel4, err := grf.AddRelationship(aNode.GetID(), bNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []graph.Relationship{rel4, rel3, rel2, rel1}, grf.ListRelationships())
+	assert.Equal(t, []graph.Relationship{rel3, rel2}, grf.Outgoing(bNode.GetID()))
+}
+
+func Test_Graph_Cursor(t *testing.T) {
+	grf := graph.New()
+	expected := []graph.Node{}
+	for i := 9; i >= 0; i-- {
+		node, _ := grf.InsertNode(fmt.Sprintf("puppy-%d", i), puppyType, bobitaBody)
+		expected = append([]graph.Node{node}, expected...)
+		grf.InsertNode(fmt.Sprintf("dragon-%d", i), dragonType, smaugBody)
+	}
+
+	cursor := grf.Cursor(3, graph.FilterNodesByLabel(puppyType))
+	// nodes added after the cursor was created are not returned
+	grf.InsertNode("puppy-10", puppyType, bobitaBody)
+	pages := [][]graph.Node{}
+	for cursor.Next() {
+		pages = append(pages, cursor.Nodes())
+	}
+	assert.NoError(t, cursor.Err())
+	assert.Equal(t, [][]graph.Node{expected[0:3], expected[3:6], expected[6:9], expected[9:]}, pages)
+	assert.False(t, cursor.Next())
+	assert.Empty(t, cursor.Nodes())
+
+	cursor = grf.Cursor(0, graph.FilterNodes(func(node graph.Node) bool { return node.GetName() == "puppy-5" || node.GetName() == "dragon-5" }))
+	assert.True(t, cursor.Next())
+	assert.Len(t, cursor.Nodes(), 2)
+	assert.Equal(t, "dragon-5", cursor.Nodes()[0].GetName())
+	assert.False(t, cursor.Next())
+}
+
+func Test_Graph_Cursor_Namespaces(t *testing.T) {
+	grf := graph.New()
+	grf.InsertNode(bobita, puppyType, bobitaBody)
+	for _, namespace := range []string{"b", "a", ""} {
+		_, err := grf.UpsertNodeInNamespace(namespace, azor, puppyType, azorBody)
+		assert.NoError(t, err)
+	}
+
+	// the nodes found in the name index are sorted as by ListNodes
+	cursor := grf.Cursor(0, graph.FilterNodesByName(azor))
+	assert.True(t, cursor.Next())
+	assert.Equal(t, grf.ListNodes(graph.FilterNodesByName(azor)), cursor.Nodes())
+	references := []string{}
+	for _, node := range cursor.Nodes() {
+		references = append(references, node.Reference())
+	}
+	assert.Equal(t, []string{azor, "a:" + azor, "b:" + azor}, references)
+}
+
+func Test_Graph_Cursor_DiskStore(t *testing.T) {
+	store, err := graph.NewTempDiskStore(t.TempDir())
+	assert.NoError(t, err)
+	grf, err := graph.NewWithStore(store)
+	assert.NoError(t, err)
+	defer grf.Close()
+	for i := 0; i < 5; i++ {
+		grf.InsertNode(fmt.Sprintf("puppy-%d", i), puppyType, bobitaBody)
+	}
+
+	names := []string{}
+	cursor := grf.Cursor(2)
+	for cursor.Next() {
+		assert.LessOrEqual(t, len(cursor.Nodes()), 2)
+		for _, node := range cursor.Nodes() {
+			names = append(names, node.GetName())
+		}
+	}
+	assert.NoError(t, cursor.Err())
+	assert.Equal(t, []string{"puppy-0", "puppy-1", "puppy-2", "puppy-3", "puppy-4"}, names)
+}
+
+func Test_Graph_RelationshipCursor(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	_, err := grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(bNode.GetID(), dNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(aNode.GetID(), bNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	cursor := grf.RelationshipCursor(2, graph.FilterRelByLabel("enemies"))
+	assert.True(t, cursor.Next())
+	assert.Equal(t, grf.ListRelationships(graph.FilterRelByLabel("enemies")), cursor.Relationships())
+	assert.False(t, cursor.Next())
+
+	all := []graph.Relationship{}
+	cursor = grf.RelationshipCursor(3)
+	for cursor.Next() {
+		all = append(all, cursor.Relationships()...)
+	}
+	assert.NoError(t, cursor.Err())
+	assert.Equal(t, grf.ListRelationships(), all)
+}
@@ -0,0 +1,185 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// StronglyConnectedComponents splits the graph into groups of nodes where every node can be reached from every other one, following only relationships having one of the given labels.
+// If no labels are given, all relationships are followed. Every node is part of exactly one component, so nodes that are not part of a cycle form a component by themselves.
+// The nodes in each component are sorted by label, then name, and the components are sorted by their first node
+func (g *Graph) StronglyConnectedComponents(labels ...string) [][]Node {
+	g.RLock()
+	defer g.RUnlock()
+	return g.stronglyConnected(labels)
+}
+
+// stronglyConnected finds the sorted strongly connected components of the graph. The caller must hold the read lock
+func (g *Graph) stronglyConnected(labels []string) [][]Node {
+	t := &tarjan{
+		graph:   g,
+		labels:  labels,
+		index:   map[string]int{},
+		low:     map[string]int{},
+		onStack: map[string]struct{}{},
+	}
+	// the nodes are visited in order, so the components are found the same way every time
+	nodes := make([]Node, 0, g.store.CountNodes())
+	g.store.ForEachNode(func(node Node) bool {
+		nodes = append(nodes, node)
+		return true
+	})
+	sort.Slice(nodes, func(i, j int) bool {
+		return lessNode(nodes[i], nodes[j])
+	})
+	for _, node := range nodes {
+		if _, ok := t.index[node.id]; !ok {
+			t.connect(node.id)
+		}
+	}
+	for _, component := range t.components {
+		sort.Slice(component, func(i, j int) bool {
+			return lessNode(component[i], component[j])
+		})
+	}
+	sort.Slice(t.components, func(i, j int) bool {
+		return lessNode(t.components[i][0], t.components[j][0])
+	})
+	return t.components
+}
+
+// tarjan holds the state of Tarjan's algorithm for finding strongly connected components
+type tarjan struct {
+	graph      *Graph
+	labels     []string
+	next       int
+	index      map[string]int
+	low        map[string]int
+	stack      []string
+	onStack    map[string]struct{}
+	components [][]Node
+}
+
+// connect visits the node and everything reachable from it, closing a component whenever the node is the first one visited in it. The caller must hold the read lock
+func (t *tarjan) connect(id string) {
+	t.index[id] = t.next
+	t.low[id] = t.next
+	t.next++
+	t.stack = append(t.stack, id)
+	t.onStack[id] = struct{}{}
+	for _, h := range t.graph.hops(id, Outbound, t.labels) {
+		if !t.graph.store.HasNode(h.to) {
+			continue
+		}
+		if _, ok := t.index[h.to]; !ok {
+			t.connect(h.to)
+			if t.low[h.to] < t.low[id] {
+				t.low[id] = t.low[h.to]
+			}
+			continue
+		}
+		if _, ok := t.onStack[h.to]; ok && t.index[h.to] < t.low[id] {
+			t.low[id] = t.index[h.to]
+		}
+	}
+	if t.low[id] != t.index[id] {
+		return
+	}
+	component := []Node{}
+	for {
+		last := t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+		delete(t.onStack, last)
+		component = append(component, t.graph.node(last))
+		if last == id {
+			break
+		}
+	}
+	t.components = append(t.components, component)
+}
+
+// Cycles returns the simple cycles in the graph, i.e.: the chains of relationships that start and end in the same node without passing through any other node twice.
+// Only relationships having one of the labels in the options are followed, always in their own direction; the direction in the options is ignored.
+// Each cycle is returned once, starting from its first node when sorted by label, then name.
+// The search stops when the context is done, returning its error, or when one of the limits in the options is reached, returning a *LimitError along with the cycles found so far.
+// The depth limit applies to the number of relationships in a cycle
+func (g *Graph) Cycles(ctx context.Context, opts PathOptions) ([]*ChainLink, error) {
+	g.RLock()
+	defer g.RUnlock()
+	components := g.stronglyConnected(opts.Labels)
+	search := &cycleSearch{
+		ctx:     ctx,
+		graph:   g,
+		opts:    opts,
+		visited: map[string]struct{}{},
+		found:   []*ChainLink{},
+	}
+	for _, component := range components {
+		// a cycle can only pass through nodes in the same component. The nodes are sorted, so each one only needs to be checked
+		// for cycles through the nodes after it, since the cycles through the ones before it were already found
+		search.allowed = map[string]struct{}{}
+		for _, node := range component {
+			search.allowed[node.id] = struct{}{}
+		}
+		for _, node := range component {
+			search.start = node.id
+			if err := search.walk(node.id, 0); err != nil {
+				return search.found, err
+			}
+			delete(search.allowed, node.id)
+		}
+	}
+	if search.truncated {
+		return search.found, &LimitError{Limit: LimitMaxDepth, Value: opts.MaxDepth}
+	}
+	return search.found, nil
+}
+
+// cycleSearch holds the state of a depth first search for the cycles passing through a node
+type cycleSearch struct {
+	ctx   context.Context
+	graph *Graph
+	opts  PathOptions
+	start string
+	// allowed holds the nodes the cycles can pass through, visited the nodes on the current path, and steps the relationships followed to reach the current node
+	allowed   map[string]struct{}
+	visited   map[string]struct{}
+	steps     []hop
+	found     []*ChainLink
+	truncated bool
+}
+
+// walk follows the relationships of the node, looking for the ones that lead back to the start. The caller must hold the read lock
+func (s *cycleSearch) walk(current string, depth int) error {
+	if err := s.ctx.Err(); err != nil {
+		return fmt.Errorf("search cancelled; %w", err)
+	}
+	s.visited[current] = struct{}{}
+	defer delete(s.visited, current)
+	for _, h := range s.graph.hops(current, Outbound, s.opts.Labels) {
+		if _, ok := s.allowed[h.to]; !ok {
+			continue
+		}
+		if _, ok := s.visited[h.to]; ok && h.to != s.start {
+			continue
+		}
+		if s.opts.MaxDepth > 0 && depth >= s.opts.MaxDepth {
+			s.truncated = true
+			return nil
+		}
+		if h.to == s.start {
+			if s.opts.MaxPaths > 0 && len(s.found) >= s.opts.MaxPaths {
+				return &LimitError{Limit: LimitMaxPaths, Value: s.opts.MaxPaths}
+			}
+			s.found = append(s.found, s.graph.chain(append(s.steps, h)))
+			continue
+		}
+		s.steps = append(s.steps, h)
+		if err := s.walk(h.to, depth+1); err != nil {
+			return err
+		}
+		s.steps = s.steps[:len(s.steps)-1]
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package graph_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+// newCycleGraph creates a graph where Bobita and Azor are friends with each other, Azor is enemies with Smaug, and Smaug is enemies with Bobita and with itself
+func newCycleGraph(t *testing.T) (*graph.Graph, graph.Node, graph.Node, graph.Node) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	for _, rel := range []struct {
+		from, to graph.Node
+		label    string
+	}{
+		{bNode, aNode, "friends"},
+		{aNode, bNode, "friends"},
+		{aNode, dNode, "enemies"},
+		{dNode, bNode, "enemies"},
+		{dNode, dNode, "enemies"},
+	} {
+		_, err := grf.AddRelationship(rel.from.GetID(), rel.to.GetID(), rel.label)
+		assert.NoError(t, err)
+	}
+	return grf, bNode, aNode, dNode
+}
+
+func Test_Graph_StronglyConnectedComponents(t *testing.T) {
+	grf, bNode, aNode, dNode := newCycleGraph(t)
+	farNode, _ := grf.InsertNode("Rex", puppyType, []byte{})
+	_, err := grf.AddRelationship(bNode.GetID(), farNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	components := grf.StronglyConnectedComponents()
+	assert.Equal(t, [][]graph.Node{{dNode, aNode, bNode}, {farNode}}, components)
+
+	components = grf.StronglyConnectedComponents("friends")
+	assert.Equal(t, [][]graph.Node{{dNode}, {aNode, bNode}, {farNode}}, components)
+
+	components = grf.StronglyConnectedComponents("enemies")
+	assert.Len(t, components, 4)
+}
+
+func Test_Graph_Cycles(t *testing.T) {
+	grf, _, _, _ := newCycleGraph(t)
+
+	cycles, err := grf.Cycles(context.Background(), graph.PathOptions{})
+	assert.NoError(t, err)
+	found := []string{}
+	for _, cycle := range cycles {
+		found = append(found, cycle.String())
+	}
+	assert.ElementsMatch(t, []string{
+		"{Asset:Smaug}->{rel:Smaug-enemies-Smaug}->{Asset:Smaug}",
+		"{Asset:Smaug}->{rel:Smaug-enemies-Bobita}->{Asset:Bobita}->{rel:Bobita-friends-Azor}->{Asset:Azor}->{rel:Azor-enemies-Smaug}->{Asset:Smaug}",
+		"{Asset:Azor}->{rel:Azor-friends-Bobita}->{Asset:Bobita}->{rel:Bobita-friends-Azor}->{Asset:Azor}",
+	}, found)
+
+	cycles, err = grf.Cycles(context.Background(), graph.PathOptions{Labels: []string{"friends"}})
+	assert.NoError(t, err)
+	assert.Len(t, cycles, 1)
+
+	cycles, err = grf.Cycles(context.Background(), graph.PathOptions{Labels: []string{"enemies"}})
+	assert.NoError(t, err)
+	assert.Len(t, cycles, 1)
+}
+
+func Test_Graph_Cycles_Limits(t *testing.T) {
+	grf, _, _, _ := newCycleGraph(t)
+	limitErr := &graph.LimitError{}
+
+	cycles, err := grf.Cycles(context.Background(), graph.PathOptions{MaxDepth: 2})
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, graph.LimitMaxDepth, limitErr.Limit)
+	assert.Len(t, cycles, 2)
+
+	cycles, err = grf.Cycles(context.Background(), graph.PathOptions{MaxPaths: 1})
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, graph.LimitMaxPaths, limitErr.Limit)
+	assert.Len(t, cycles, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = grf.Cycles(ctx, graph.PathOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,285 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// NodeKey identifies a node by its namespace, label and name, which, unlike its ID, stay the same across graphs built from the same data
+type NodeKey struct {
+	Namespace string `json:"namespace,omitempty"`
+	Label     string `json:"label"`
+	Name      string `json:"name"`
+}
+
+func (k NodeKey) String() string {
+	if k.Namespace == "" {
+		return fmt.Sprintf("%s %s", k.Label, k.Name)
+	}
+	return fmt.Sprintf("%s %s%s%s", k.Label, k.Namespace, NamespaceSeparator, k.Name)
+}
+
+func keyOf(node Node) NodeKey {
+	return NodeKey{Namespace: node.namespace, Label: node.label, Name: node.name}
+}
+
+// RelationshipKey identifies a relationship by its label, the keys of the nodes it connects and its properties
+type RelationshipKey struct {
+	From       NodeKey           `json:"from"`
+	Label      string            `json:"label"`
+	To         NodeKey           `json:"to"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+func (k RelationshipKey) String() string {
+	if len(k.Properties) == 0 {
+		return fmt.Sprintf("%s -%s-> %s", k.From, k.Label, k.To)
+	}
+	properties := make([]string, 0, len(k.Properties))
+	for key, value := range k.Properties {
+		properties = append(properties, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(properties)
+	return fmt.Sprintf("%s -%s {%s}-> %s", k.From, k.Label, strings.Join(properties, ", "), k.To)
+}
+
+// id encodes the key, so that keys can be compared; the properties are encoded sorted by name
+func (k RelationshipKey) id() string {
+	encoded, _ := json.Marshal(k)
+	return string(encoded)
+}
+
+// FieldChange is a top level field of a json body that differs between two versions of a node. Before is empty if the field was added, and After if it was removed.
+// If either body is not a json object, the whole body is reported as a single change with an empty field name
+type FieldChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// NodeChange describes a node that exists in both graphs, but with a different body
+type NodeChange struct {
+	NodeKey
+	Fields []FieldChange `json:"fields"`
+}
+
+// Diff holds the differences between two graphs. All the lists are sorted
+type Diff struct {
+	AddedNodes           []NodeKey         `json:"addedNodes"`
+	RemovedNodes         []NodeKey         `json:"removedNodes"`
+	ModifiedNodes        []NodeChange      `json:"modifiedNodes"`
+	AddedRelationships   []RelationshipKey `json:"addedRelationships"`
+	RemovedRelationships []RelationshipKey `json:"removedRelationships"`
+}
+
+// Empty returns true if the two graphs hold the same nodes and relationships
+func (d *Diff) Empty() bool {
+	return len(d.AddedNodes) == 0 && len(d.RemovedNodes) == 0 && len(d.ModifiedNodes) == 0 &&
+		len(d.AddedRelationships) == 0 && len(d.RemovedRelationships) == 0
+}
+
+// Compare returns what changed from the before graph to the after one. Nodes are matched by their namespace, label and name, and relationships by their label, the nodes they connect and their properties,
+// so a relationship whose properties changed is reported as removed and added again.
+// If more than one node in the same graph has the same namespace, label and name, ErrAmbiguousNode is returned, and if the store of either graph fails to read it, its error is
+func Compare(before, after *Graph) (*Diff, error) {
+	diff := &Diff{
+		AddedNodes:           []NodeKey{},
+		RemovedNodes:         []NodeKey{},
+		ModifiedNodes:        []NodeChange{},
+		AddedRelationships:   []RelationshipKey{},
+		RemovedRelationships: []RelationshipKey{},
+	}
+	if before == after {
+		return diff, nil
+	}
+	// the graphs are read one after the other, so that Compare never holds the locks of both
+	beforeNodes, beforeRels, err := before.diffKeys()
+	if err != nil {
+		return nil, err
+	}
+	afterNodes, afterRels, err := after.diffKeys()
+	if err != nil {
+		return nil, err
+	}
+	for key, node := range afterNodes {
+		old, ok := beforeNodes[key]
+		if !ok {
+			diff.AddedNodes = append(diff.AddedNodes, key)
+			continue
+		}
+		if fields := compareBodies(old.Body, node.Body); len(fields) > 0 {
+			diff.ModifiedNodes = append(diff.ModifiedNodes, NodeChange{NodeKey: key, Fields: fields})
+		}
+	}
+	for key := range beforeNodes {
+		if _, ok := afterNodes[key]; !ok {
+			diff.RemovedNodes = append(diff.RemovedNodes, key)
+		}
+	}
+
+	for id, key := range afterRels {
+		if _, ok := beforeRels[id]; !ok {
+			diff.AddedRelationships = append(diff.AddedRelationships, key)
+		}
+	}
+	for id, key := range beforeRels {
+		if _, ok := afterRels[id]; !ok {
+			diff.RemovedRelationships = append(diff.RemovedRelationships, key)
+		}
+	}
+
+	sortNodeKeys(diff.AddedNodes)
+	sortNodeKeys(diff.RemovedNodes)
+	sort.Slice(diff.ModifiedNodes, func(i, j int) bool {
+		return lessNodeKey(diff.ModifiedNodes[i].NodeKey, diff.ModifiedNodes[j].NodeKey)
+	})
+	sortRelationshipKeys(diff.AddedRelationships)
+	sortRelationshipKeys(diff.RemovedRelationships)
+	return diff, nil
+}
+
+// diffKeys returns the nodes of the graph by their key, and the keys of its relationships, read while holding the read lock
+func (g *Graph) diffKeys() (map[NodeKey]Node, map[string]RelationshipKey, error) {
+	g.RLock()
+	defer g.RUnlock()
+	nodes, err := g.nodesByKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	rels := g.relationshipKeys()
+	if err := g.store.Err(); err != nil {
+		return nil, nil, fmt.Errorf("could not read the graph; %w", err)
+	}
+	return nodes, rels, nil
+}
+
+// nodesByKey returns the nodes of the graph by their key. The caller must hold the read lock
+func (g *Graph) nodesByKey() (map[NodeKey]Node, error) {
+	nodes := make(map[NodeKey]Node, g.store.CountNodes())
+	var err error
+	g.store.ForEachNode(func(node Node) bool {
+		key := keyOf(node)
+		if _, ok := nodes[key]; ok {
+			err = fmt.Errorf("%w; found more than one node with name '%s' and label '%s' in namespace '%s'", ErrAmbiguousNode, node.name, node.label, node.namespace)
+			return false
+		}
+		nodes[key] = node
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// relationshipKeys returns the keys of all the relationships in the graph, by their encoding. The caller must hold the read lock
+func (g *Graph) relationshipKeys() map[string]RelationshipKey {
+	keys := make(map[string]RelationshipKey, g.store.CountRelationships())
+	g.store.ForEachRelationship(func(rel Relationship) bool {
+		key := RelationshipKey{
+			From:       keyOf(g.node(rel.From)),
+			Label:      rel.Label,
+			To:         keyOf(g.node(rel.To)),
+			Properties: rel.Properties,
+		}
+		keys[key.id()] = key
+		return true
+	})
+	return keys
+}
+
+// compareBodies returns the top level fields that differ between the two bodies. Json values are compared by their content, not by how they are formatted.
+// An empty body is treated as an object without fields
+func compareBodies(before, after []byte) []FieldChange {
+	beforeFields, beforeOK := bodyFields(before)
+	afterFields, afterOK := bodyFields(after)
+	if !beforeOK || !afterOK {
+		if sameJSON(before, after) {
+			return nil
+		}
+		return []FieldChange{{Before: rawBody(before), After: rawBody(after)}}
+	}
+	changes := []FieldChange{}
+	for field, value := range afterFields {
+		old, ok := beforeFields[field]
+		if !ok || !sameJSON(old, value) {
+			changes = append(changes, FieldChange{Field: field, Before: old, After: value})
+		}
+	}
+	for field, old := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			changes = append(changes, FieldChange{Field: field, Before: old})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// bodyFields decodes the top level fields of a json object. ok is false if the body is neither empty nor a json object
+func bodyFields(body []byte) (fields map[string]json.RawMessage, ok bool) {
+	fields = map[string]json.RawMessage{}
+	if len(body) == 0 {
+		return fields, true
+	}
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return nil, false
+	}
+	return fields, true
+}
+
+// sameJSON compares two bodies by their decoded json content, falling back to comparing the bytes if either is not valid json
+func sameJSON(a, b []byte) bool {
+	var decodedA, decodedB interface{}
+	if json.Unmarshal(a, &decodedA) != nil || json.Unmarshal(b, &decodedB) != nil {
+		return string(a) == string(b)
+	}
+	return reflect.DeepEqual(decodedA, decodedB)
+}
+
+// rawBody returns the body as json; bodies that are not valid json are encoded as a string
+func rawBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return body
+	}
+	encoded, _ := json.Marshal(string(body))
+	return encoded
+}
+
+func lessNodeKey(a, b NodeKey) bool {
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	if a.Label != b.Label {
+		return a.Label < b.Label
+	}
+	return a.Name < b.Name
+}
+
+func sortNodeKeys(keys []NodeKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		return lessNodeKey(keys[i], keys[j])
+	})
+}
+
+func sortRelationshipKeys(keys []RelationshipKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].From != keys[j].From {
+			return lessNodeKey(keys[i].From, keys[j].From)
+		}
+		if keys[i].Label != keys[j].Label {
+			return keys[i].Label < keys[j].Label
+		}
+		if keys[i].To != keys[j].To {
+			return lessNodeKey(keys[i].To, keys[j].To)
+		}
+		return keys[i].id() < keys[j].id()
+	})
+}
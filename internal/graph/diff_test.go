@@ -0,0 +1,168 @@
+package graph_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_Compare(t *testing.T) {
+	before := graph.New()
+	bNode, _ := before.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := before.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := before.InsertNode(smaug, dragonType, smaugBody)
+	_, err := before.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = before.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	after := graph.New()
+	// the same bobita, with the fields in a different order
+	bNode, _ = after.InsertNode(bobita, puppyType, []byte(`{"power":500,"name":"Bobita"}`))
+	aNode, _ = after.InsertNode(azor, puppyType, []byte(`{"name":"Azor","power":9000,"owner":"Tom"}`))
+	rNode, _ := after.InsertNode("Rex", puppyType, []byte{})
+	_, err = after.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = after.AddRelationship(rNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	diff, err := graph.Compare(before, after)
+	assert.NoError(t, err)
+	assert.False(t, diff.Empty())
+	assert.Equal(t, []graph.NodeKey{{Label: puppyType, Name: "Rex"}}, diff.AddedNodes)
+	assert.Equal(t, []graph.NodeKey{{Label: dragonType, Name: smaug}}, diff.RemovedNodes)
+	assert.Equal(t, []graph.NodeChange{{
+		NodeKey: graph.NodeKey{Label: puppyType, Name: azor},
+		Fields: []graph.FieldChange{
+			{Field: "owner", After: json.RawMessage(`"Tom"`)},
+			{Field: "power", Before: json.RawMessage(`457`), After: json.RawMessage(`9000`)},
+		},
+	}}, diff.ModifiedNodes)
+	assert.Equal(t, []graph.RelationshipKey{{
+		From:  graph.NodeKey{Label: puppyType, Name: "Rex"},
+		Label: "friends",
+		To:    graph.NodeKey{Label: puppyType, Name: azor},
+	}}, diff.AddedRelationships)
+	assert.Equal(t, []graph.RelationshipKey{{
+		From:  graph.NodeKey{Label: dragonType, Name: smaug},
+		Label: "enemies",
+		To:    graph.NodeKey{Label: puppyType, Name: bobita},
+	}}, diff.RemovedRelationships)
+	assert.Equal(t, "dragon Smaug -enemies-> puppy Bobita", diff.RemovedRelationships[0].String())
+}
+
+func Test_Compare_RelationshipProperties(t *testing.T) {
+	before := graph.New()
+	bNode, _ := before.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := before.InsertNode(azor, puppyType, azorBody)
+	_, err := before.AddRelationshipWithProperties(bNode.GetID(), aNode.GetID(), "friends", map[string]string{"since": "2020"})
+	assert.NoError(t, err)
+	after := graph.New()
+	bNode, _ = after.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ = after.InsertNode(azor, puppyType, azorBody)
+	_, err = after.AddRelationshipWithProperties(bNode.GetID(), aNode.GetID(), "friends", map[string]string{"since": "2021"})
+	assert.NoError(t, err)
+
+	diff, err := graph.Compare(before, after)
+	assert.NoError(t, err)
+	assert.Empty(t, diff.AddedNodes)
+	assert.Len(t, diff.AddedRelationships, 1)
+	assert.Len(t, diff.RemovedRelationships, 1)
+	assert.Equal(t, "puppy Bobita -friends {since=2021}-> puppy Azor", diff.AddedRelationships[0].String())
+	assert.Equal(t, map[string]string{"since": "2020"}, diff.RemovedRelationships[0].Properties)
+}
+
+func Test_Compare_Concurrent(t *testing.T) {
+	a := graph.New()
+	b := graph.New()
+	done := make(chan struct{})
+	var writers, wg sync.WaitGroup
+	for _, grf := range []*graph.Graph{a, b} {
+		writers.Add(1)
+		go func(grf *graph.Graph) {
+			defer writers.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				grf.InsertNode(fmt.Sprintf("item-%d", i), puppyType, []byte{})
+			}
+		}(grf)
+	}
+	// comparing both ways at once must not deadlock with the writers waiting for the locks
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := graph.Compare(a, b)
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := graph.Compare(b, a)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	close(done)
+	writers.Wait()
+}
+
+func Test_Compare_Same(t *testing.T) {
+	before := graph.New()
+	before.InsertNode(bobita, puppyType, bobitaBody)
+	after := graph.New()
+	after.InsertNode(bobita, puppyType, bobitaBody)
+
+	diff, err := graph.Compare(before, after)
+	assert.NoError(t, err)
+	assert.True(t, diff.Empty())
+
+	diff, err = graph.Compare(before, before)
+	assert.NoError(t, err)
+	assert.True(t, diff.Empty())
+}
+
+func Test_Compare_NotObjects(t *testing.T) {
+	before := graph.New()
+	before.InsertNode(bobita, puppyType, []byte(`not json`))
+	after := graph.New()
+	after.InsertNode(bobita, puppyType, []byte(`[1,2]`))
+
+	diff, err := graph.Compare(before, after)
+	assert.NoError(t, err)
+	assert.Equal(t, []graph.FieldChange{{Before: json.RawMessage(`"not json"`), After: json.RawMessage(`[1,2]`)}}, diff.ModifiedNodes[0].Fields)
+}
+
+func Test_Compare_Ambiguous(t *testing.T) {
+	before := graph.New()
+	before.InsertNode(bobita, puppyType, bobitaBody)
+	before.InsertNode(bobita, puppyType, azorBody)
+
+	_, err := graph.Compare(before, graph.New())
+	assert.ErrorIs(t, err, graph.ErrAmbiguousNode)
+}
+
+func Test_Compare_Namespaces(t *testing.T) {
+	before := graph.New()
+	_, err := before.UpsertNodeInNamespace("a", bobita, puppyType, bobitaBody)
+	assert.NoError(t, err)
+
+	after := graph.New()
+	_, err = after.UpsertNodeInNamespace("b", bobita, puppyType, bobitaBody)
+	assert.NoError(t, err)
+
+	// the same node in another namespace is a different node
+	diff, err := graph.Compare(before, after)
+	assert.NoError(t, err)
+	assert.Equal(t, []graph.NodeKey{{Namespace: "b", Label: puppyType, Name: bobita}}, diff.AddedNodes)
+	assert.Equal(t, []graph.NodeKey{{Namespace: "a", Label: puppyType, Name: bobita}}, diff.RemovedNodes)
+	assert.Empty(t, diff.ModifiedNodes)
+}
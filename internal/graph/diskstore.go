@@ -0,0 +1,421 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrCorruptStore = errors.New("the store file is corrupt")
+	ErrStoreClosed  = errors.New("the store is closed")
+)
+
+// kinds of the records appended to the file of a disk store
+const (
+	putNodeRecord            byte = 'n'
+	deleteNodeRecord         byte = 'N'
+	putRelationshipRecord    byte = 'r'
+	deleteRelationshipRecord byte = 'R'
+)
+
+// recordHeaderSize is the size of the kind and the length that precede the payload of each record
+const recordHeaderSize = 5
+
+// recordPosition is where the payload of a record is found in the file of a disk store
+type recordPosition struct {
+	offset int64
+	length uint32
+}
+
+// diskStore keeps the nodes and relationships in a file, and only their positions in the file and the indexes in memory, so the memory it uses grows with the number
+// of nodes, relationships and index keys, but not with the size of the bodies. The file is an append-only log that is never compacted: every change appends a record,
+// and the last record written for an ID wins, so the file grows with every change, including deletes.
+// Since records are never overwritten, a view only needs the positions and indexes as they were when it was taken, which are kept in persistent maps shared with the views
+type diskStore struct {
+	indexSet
+	log           *diskLog
+	nodes         persistentMap
+	relationships persistentMap
+	// edit marks the parts of the maps created since the last view was taken, as for the memory store
+	edit *editToken
+	// readOnly is set for views, which hold the error of the first change made through them
+	readOnly bool
+	err      error
+}
+
+// diskLog is the file of a disk store, shared by the store and its views. The lock guards the writer and the error, since views read while the store writes
+type diskLog struct {
+	sync.Mutex
+	file      *os.File
+	writer    *bufio.Writer
+	temporary bool
+	closed    bool
+	// size is the length of the file, including the records still buffered by the writer
+	size int64
+	err  error
+}
+
+// OpenDiskStore opens the store kept in the file at the given path, creating the file if it does not exist.
+// The nodes and relationships written by a previous run are kept, and indexed again when the store is passed to NewWithStore
+func OpenDiskStore(path string) (Store, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open store file %s; %w", path, err)
+	}
+	s := newDiskStore(file)
+	if err := s.replay(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not read store file %s; %w", path, err)
+	}
+	return s, nil
+}
+
+// NewTempDiskStore creates an empty store kept in a new file in the given directory, or in the default directory for temporary files if dir is empty.
+// The file is removed when the store is closed, or right away on systems that allow removing open files
+func NewTempDiskStore(dir string) (Store, error) {
+	file, err := os.CreateTemp(dir, "cyscale-*.store")
+	if err != nil {
+		return nil, fmt.Errorf("could not create store file; %w", err)
+	}
+	s := newDiskStore(file)
+	s.log.temporary = os.Remove(file.Name()) != nil
+	return s, nil
+}
+
+func newDiskStore(file *os.File) *diskStore {
+	return &diskStore{
+		log:  &diskLog{file: file, writer: bufio.NewWriter(file)},
+		edit: &editToken{},
+	}
+}
+
+// replay reads all the records in the file to find the last position of each node and relationship.
+// A record cut short, as left by a run that stopped while writing it, is dropped along with anything after it
+func (s *diskStore) replay() error {
+	reader := bufio.NewReader(s.log.file)
+	header := make([]byte, recordHeaderSize)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return err
+		}
+		kind, length := header[0], binary.BigEndian.Uint32(header[1:])
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return err
+		}
+		position := recordPosition{offset: s.log.size + recordHeaderSize, length: length}
+		switch kind {
+		case putNodeRecord:
+			node := snapshotNode{}
+			if err := json.Unmarshal(payload, &node); err != nil {
+				return fmt.Errorf("%w; node at offset %d; %s", ErrCorruptStore, s.log.size, err.Error())
+			}
+			s.nodes = s.nodes.set(s.edit, node.ID, position)
+		case deleteNodeRecord:
+			s.nodes = s.nodes.delete(s.edit, string(payload))
+		case putRelationshipRecord:
+			rel := Relationship{}
+			if err := json.Unmarshal(payload, &rel); err != nil {
+				return fmt.Errorf("%w; relationship at offset %d; %s", ErrCorruptStore, s.log.size, err.Error())
+			}
+			s.relationships = s.relationships.set(s.edit, rel.ID, position)
+		case deleteRelationshipRecord:
+			s.relationships = s.relationships.delete(s.edit, string(payload))
+		default:
+			return fmt.Errorf("%w; unknown record kind %q at offset %d", ErrCorruptStore, kind, s.log.size)
+		}
+		s.log.size += recordHeaderSize + int64(length)
+	}
+	if err := s.log.file.Truncate(s.log.size); err != nil {
+		return err
+	}
+	_, err := s.log.file.Seek(s.log.size, io.SeekStart)
+	return err
+}
+
+// append writes a record at the end of the file and returns the position of its payload
+func (l *diskLog) append(kind byte, payload []byte) (recordPosition, bool) {
+	l.Lock()
+	defer l.Unlock()
+	if l.err != nil {
+		return recordPosition{}, false
+	}
+	header := make([]byte, recordHeaderSize)
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := l.writer.Write(header); err != nil {
+		l.fail(err)
+		return recordPosition{}, false
+	}
+	if _, err := l.writer.Write(payload); err != nil {
+		l.fail(err)
+		return recordPosition{}, false
+	}
+	position := recordPosition{offset: l.size + recordHeaderSize, length: uint32(len(payload))}
+	l.size += recordHeaderSize + int64(len(payload))
+	return position, true
+}
+
+// read returns the payload of the record at the position, flushing the records still buffered by the writer first
+func (l *diskLog) read(position recordPosition) ([]byte, bool) {
+	l.Lock()
+	if l.err == nil && l.writer.Buffered() > 0 {
+		if err := l.writer.Flush(); err != nil {
+			l.fail(err)
+		}
+	}
+	failed := l.err != nil
+	l.Unlock()
+	if failed {
+		return nil, false
+	}
+	payload := make([]byte, position.length)
+	if _, err := l.file.ReadAt(payload, position.offset); err != nil {
+		l.Lock()
+		l.fail(err)
+		l.Unlock()
+		return nil, false
+	}
+	return payload, true
+}
+
+// fail remembers the first error the store runs into. The caller must hold the lock
+func (l *diskLog) fail(err error) {
+	if l.err == nil {
+		l.err = fmt.Errorf("could not access store file %s; %w", l.file.Name(), err)
+	}
+}
+
+// writable returns false for views, which can not be changed
+func (s *diskStore) writable() bool {
+	if s.readOnly {
+		if s.err == nil {
+			s.err = ErrReadOnly
+		}
+		return false
+	}
+	return true
+}
+
+// corrupt remembers that a record could not be decoded
+func (s *diskStore) corrupt(err error) {
+	s.log.Lock()
+	defer s.log.Unlock()
+	s.log.fail(err)
+}
+
+func (s *diskStore) PutNode(node Node) {
+	if !s.writable() {
+		return
+	}
+	payload, err := json.Marshal(toSnapshotNode(node))
+	if err != nil {
+		s.corrupt(err)
+		return
+	}
+	if position, ok := s.log.append(putNodeRecord, payload); ok {
+		s.nodes = s.nodes.set(s.edit, node.id, position)
+	}
+}
+
+func (s *diskStore) GetNode(id string) (Node, bool) {
+	position, ok := s.nodes.get(id)
+	if !ok {
+		return Node{}, false
+	}
+	payload, ok := s.log.read(position.(recordPosition))
+	if !ok {
+		return Node{}, false
+	}
+	node := snapshotNode{}
+	if err := json.Unmarshal(payload, &node); err != nil {
+		s.corrupt(fmt.Errorf("%w; node %s; %s", ErrCorruptStore, id, err.Error()))
+		return Node{}, false
+	}
+	return node.node(), true
+}
+
+func (s *diskStore) HasNode(id string) bool {
+	return s.nodes.has(id)
+}
+
+func (s *diskStore) DeleteNode(id string) {
+	if !s.nodes.has(id) || !s.writable() {
+		return
+	}
+	if _, ok := s.log.append(deleteNodeRecord, []byte(id)); ok {
+		s.nodes = s.nodes.delete(s.edit, id)
+	}
+}
+
+// ForEachNode reads the nodes in the order they are found in the file, so that the file is read sequentially
+func (s *diskStore) ForEachNode(fn func(node Node) bool) {
+	for _, id := range byOffset(s.nodes) {
+		node, ok := s.GetNode(id)
+		if !ok {
+			return
+		}
+		if !fn(node) {
+			return
+		}
+	}
+}
+
+func (s *diskStore) CountNodes() int {
+	return s.nodes.len()
+}
+
+func (s *diskStore) PutRelationship(rel Relationship) {
+	if !s.writable() {
+		return
+	}
+	payload, err := json.Marshal(rel)
+	if err != nil {
+		s.corrupt(err)
+		return
+	}
+	if position, ok := s.log.append(putRelationshipRecord, payload); ok {
+		s.relationships = s.relationships.set(s.edit, rel.ID, position)
+	}
+}
+
+func (s *diskStore) GetRelationship(id string) (Relationship, bool) {
+	position, ok := s.relationships.get(id)
+	if !ok {
+		return Relationship{}, false
+	}
+	payload, ok := s.log.read(position.(recordPosition))
+	if !ok {
+		return Relationship{}, false
+	}
+	rel := Relationship{}
+	if err := json.Unmarshal(payload, &rel); err != nil {
+		s.corrupt(fmt.Errorf("%w; relationship %s; %s", ErrCorruptStore, id, err.Error()))
+		return Relationship{}, false
+	}
+	return rel, true
+}
+
+func (s *diskStore) DeleteRelationship(id string) {
+	if !s.relationships.has(id) || !s.writable() {
+		return
+	}
+	if _, ok := s.log.append(deleteRelationshipRecord, []byte(id)); ok {
+		s.relationships = s.relationships.delete(s.edit, id)
+	}
+}
+
+// ForEachRelationship reads the relationships in the order they are found in the file, so that the file is read sequentially
+func (s *diskStore) ForEachRelationship(fn func(rel Relationship) bool) {
+	for _, id := range byOffset(s.relationships) {
+		rel, ok := s.GetRelationship(id)
+		if !ok {
+			return
+		}
+		if !fn(rel) {
+			return
+		}
+	}
+}
+
+func (s *diskStore) CountRelationships() int {
+	return s.relationships.len()
+}
+
+func (s *diskStore) Index(index, key, id string) {
+	if s.writable() {
+		s.indexSet.add(s.edit, index, key, id)
+	}
+}
+
+func (s *diskStore) Unindex(index, key, id string) {
+	if s.writable() {
+		s.indexSet.remove(s.edit, index, key, id)
+	}
+}
+
+// View shares the positions and indexes with the view, so that taking it does not copy anything; the store starts a new edit token, as the memory store does
+func (s *diskStore) View() Store {
+	if s.readOnly {
+		return s
+	}
+	s.edit = &editToken{}
+	return &diskStore{
+		indexSet:      s.indexSet,
+		log:           s.log,
+		nodes:         s.nodes,
+		relationships: s.relationships,
+		readOnly:      true,
+	}
+}
+
+func (s *diskStore) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.log.Lock()
+	defer s.log.Unlock()
+	return s.log.err
+}
+
+// Close writes the records still buffered and closes the file, removing it if the store is temporary. Closing a view does nothing,
+// while closing the store also closes its views
+func (s *diskStore) Close() error {
+	if s.readOnly {
+		return nil
+	}
+	s.log.Lock()
+	defer s.log.Unlock()
+	if s.log.closed {
+		return ErrStoreClosed
+	}
+	err := s.log.writer.Flush()
+	if closeErr := s.log.file.Close(); err == nil {
+		err = closeErr
+	}
+	if s.log.temporary {
+		if removeErr := os.Remove(s.log.file.Name()); err == nil {
+			err = removeErr
+		}
+	}
+	s.log.fail(ErrStoreClosed)
+	s.log.closed = true
+	if err != nil {
+		return fmt.Errorf("could not close store file; %w", err)
+	}
+	return nil
+}
+
+// byOffset returns the IDs of the records, sorted by their position in the file
+func byOffset(positions persistentMap) []string {
+	type record struct {
+		id     string
+		offset int64
+	}
+	records := make([]record, 0, positions.len())
+	positions.forEach(func(id string, position interface{}) bool {
+		records = append(records, record{id: id, offset: position.(recordPosition).offset})
+		return true
+	})
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].offset < records[j].offset
+	})
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.id)
+	}
+	return ids
+}
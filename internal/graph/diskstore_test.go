@@ -0,0 +1,121 @@
+package graph_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_DiskStore_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.store")
+	store, err := graph.OpenDiskStore(path)
+	assert.NoError(t, err)
+	grf, err := graph.NewWithStore(store)
+	assert.NoError(t, err)
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	rel, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	assert.NoError(t, grf.DeleteNode(dNode.GetID(), graph.CascadeRelationships))
+	assert.NoError(t, grf.Close())
+
+	store, err = graph.OpenDiskStore(path)
+	assert.NoError(t, err)
+	reopened, err := graph.NewWithStore(store)
+	assert.NoError(t, err)
+	defer reopened.Close()
+	assert.ElementsMatch(t, []graph.Node{bNode, aNode}, reopened.ListNodes())
+	assert.Equal(t, []graph.Node{aNode}, reopened.ListNodes(graph.FilterNodesByName(azor)))
+	assert.Equal(t, []graph.Relationship{rel}, reopened.ListRelationships())
+	assert.Equal(t, []graph.Relationship{rel}, reopened.ListRelationships(graph.FilterRelByTo(aNode.GetID())))
+}
+
+func Test_DiskStore_TruncatedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.store")
+	store, err := graph.OpenDiskStore(path)
+	assert.NoError(t, err)
+	grf, err := graph.NewWithStore(store)
+	assert.NoError(t, err)
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	grf.InsertNode(azor, puppyType, azorBody)
+	assert.NoError(t, grf.Close())
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Truncate(path, info.Size()-3))
+
+	store, err = graph.OpenDiskStore(path)
+	assert.NoError(t, err)
+	reopened, err := graph.NewWithStore(store)
+	assert.NoError(t, err)
+	assert.Equal(t, []graph.Node{bNode}, reopened.ListNodes())
+
+	// the partial record is dropped, so new records are readable after reopening again
+	aNode, _ := reopened.InsertNode(azor, puppyType, azorBody)
+	assert.NoError(t, reopened.Close())
+	store, err = graph.OpenDiskStore(path)
+	assert.NoError(t, err)
+	reopened, err = graph.NewWithStore(store)
+	assert.NoError(t, err)
+	defer reopened.Close()
+	assert.ElementsMatch(t, []graph.Node{bNode, aNode}, reopened.ListNodes())
+}
+
+func Test_DiskStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.store")
+	assert.NoError(t, os.WriteFile(path, []byte("x\x00\x00\x00\x02{}"), 0644))
+	_, err := graph.OpenDiskStore(path)
+	assert.True(t, errors.Is(err, graph.ErrCorruptStore))
+}
+
+func Test_DiskStore_Closed(t *testing.T) {
+	dir := t.TempDir()
+	store, err := graph.NewTempDiskStore(dir)
+	assert.NoError(t, err)
+	grf, err := graph.NewWithStore(store)
+	assert.NoError(t, err)
+	node, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	assert.NoError(t, grf.Close())
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	_, err = grf.GetNodeByID(node.GetID())
+	assert.Error(t, err)
+	assert.True(t, errors.Is(grf.Err(), graph.ErrStoreClosed))
+}
+
+func Test_DiskStore_FailedReads(t *testing.T) {
+	store, err := graph.NewTempDiskStore(t.TempDir())
+	assert.NoError(t, err)
+	grf, err := graph.NewWithStore(store)
+	assert.NoError(t, err)
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	_, err = grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	assert.NoError(t, grf.Close())
+
+	// the nodes can no longer be read, so nothing that is computed from them is returned
+	_, err = grf.Stats()
+	assert.ErrorIs(t, err, graph.ErrStoreClosed)
+	_, err = graph.Compare(graph.New(), grf)
+	assert.ErrorIs(t, err, graph.ErrStoreClosed)
+	_, err = grf.Neighbourhood(bNode.GetID(), 1, graph.Both)
+	assert.ErrorIs(t, err, graph.ErrStoreClosed)
+	var b bytes.Buffer
+	assert.ErrorIs(t, grf.WriteDOT(&b, graph.ExportOptions{}), graph.ErrStoreClosed)
+	assert.ErrorIs(t, grf.WriteMermaid(&b, graph.ExportOptions{}), graph.ErrStoreClosed)
+	assert.ErrorIs(t, grf.WriteGraphML(&b, graph.ExportOptions{}), graph.ErrStoreClosed)
+	assert.Empty(t, b.String())
+}
@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// NodeStyle describes how the nodes having a certain label are drawn
+type NodeStyle struct {
+	Shape string
+	Color string
+}
+
+var (
+	defaultShapes = []string{"box", "ellipse", "octagon", "folder", "hexagon", "diamond", "component", "cylinder"}
+	defaultColors = []string{"lightblue", "lightgoldenrod", "lightpink", "palegreen", "lightsalmon", "plum", "lightcyan", "wheat"}
+)
+
+const highlightColor = "red"
+
+// ExportOptions selects what part of the graph is exported, and how it is drawn
+type ExportOptions struct {
+	// Labels are the labels of the nodes that are exported. If empty, all nodes are exported. Relationships are only exported if both their nodes are
+	Labels []string
+	// Styles maps node labels to the style used to draw them. Labels that are missing get a style assigned based on their order
+	Styles map[string]NodeStyle
+	// Highlight contains the IDs of the nodes that are highlighted
+	Highlight []string
+	// HighlightPaths contains chains whose nodes and relationships are highlighted
+	HighlightPaths []*ChainLink
+}
+
+// exportView is the part of the graph selected for exporting, sorted so that the output is the same between runs
+type exportView struct {
+	nodes            []Node
+	relationships    []Relationship
+	styles           map[string]NodeStyle
+	highlightedNodes map[string]struct{}
+	highlightedRels  map[string]struct{}
+}
+
+// exportView reads the part of the graph selected by the options. If the store fails to read the graph, its error is returned instead
+func (g *Graph) exportView(opts ExportOptions) (exportView, error) {
+	view := exportView{
+		styles:           map[string]NodeStyle{},
+		highlightedNodes: map[string]struct{}{},
+		highlightedRels:  map[string]struct{}{},
+	}
+	if len(opts.Labels) > 0 {
+		view.nodes = g.ListNodes(FilterNodesByLabel(opts.Labels...))
+	} else {
+		view.nodes = g.ListNodes()
+	}
+	exported := map[string]struct{}{}
+	labels := []string{}
+	for _, node := range view.nodes {
+		exported[node.id] = struct{}{}
+		if _, ok := view.styles[node.label]; !ok {
+			view.styles[node.label] = NodeStyle{}
+			labels = append(labels, node.label)
+		}
+	}
+	sort.Strings(labels)
+	for i, label := range labels {
+		style, ok := opts.Styles[label]
+		if !ok {
+			style = NodeStyle{Shape: defaultShapes[i%len(defaultShapes)], Color: defaultColors[i%len(defaultColors)]}
+		}
+		view.styles[label] = style
+	}
+	view.relationships = g.ListRelationships(func(rel Relationship) bool {
+		_, fromOK := exported[rel.From]
+		_, toOK := exported[rel.To]
+		return fromOK && toOK
+	})
+	for _, id := range opts.Highlight {
+		view.highlightedNodes[id] = struct{}{}
+	}
+	for _, chain := range opts.HighlightPaths {
+		for _, node := range chain.Nodes() {
+			view.highlightedNodes[node.id] = struct{}{}
+		}
+		for _, rel := range chain.Relationships() {
+			view.highlightedRels[rel.ID] = struct{}{}
+		}
+	}
+	if err := g.Err(); err != nil {
+		return exportView{}, fmt.Errorf("could not read the graph; %w", err)
+	}
+	return view, nil
+}
+
+// WriteDOT writes the graph in the Graphviz DOT format. The node label decides the shape and colour of each node, and relationships are labeled with their label
+func (g *Graph) WriteDOT(w io.Writer, opts ExportOptions) error {
+	view, err := g.exportView(opts)
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph assets {")
+	fmt.Fprintln(out, "\trankdir=LR;")
+	fmt.Fprintln(out, "\tnode [style=filled];")
+	for _, node := range view.nodes {
+		style := view.styles[node.label]
+		attributes := fmt.Sprintf("label=%s, shape=%s, fillcolor=%s", dotQuote(node.Reference()), style.Shape, dotQuote(style.Color))
+		if _, ok := view.highlightedNodes[node.id]; ok {
+			attributes += fmt.Sprintf(", color=%s, penwidth=3", highlightColor)
+		}
+		fmt.Fprintf(out, "\t%s [%s];\n", dotQuote(node.id), attributes)
+	}
+	for _, rel := range view.relationships {
+		attributes := fmt.Sprintf("label=%s", dotQuote(rel.Label))
+		if _, ok := view.highlightedRels[rel.ID]; ok {
+			attributes += fmt.Sprintf(", color=%s, fontcolor=%s, penwidth=3", highlightColor, highlightColor)
+		}
+		fmt.Fprintf(out, "\t%s -> %s [%s];\n", dotQuote(rel.From), dotQuote(rel.To), attributes)
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
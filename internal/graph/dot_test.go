@@ -0,0 +1,90 @@
+package graph_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_Graph_WriteDOT(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = grf.WriteDOT(&buf, graph.ExportOptions{
+		Styles: map[string]graph.NodeStyle{puppyType: {Shape: "box", Color: "yellow"}},
+	})
+	assert.NoError(t, err)
+	expected := strings.Join([]string{
+		"digraph assets {",
+		"\trankdir=LR;",
+		"\tnode [style=filled];",
+		fmt.Sprintf("\t\"%s\" [label=\"Smaug\", shape=box, fillcolor=\"lightblue\"];", dNode.GetID()),
+		fmt.Sprintf("\t\"%s\" [label=\"Azor\", shape=box, fillcolor=\"yellow\"];", aNode.GetID()),
+		fmt.Sprintf("\t\"%s\" [label=\"Bobita\", shape=box, fillcolor=\"yellow\"];", bNode.GetID()),
+		fmt.Sprintf("\t\"%s\" -> \"%s\" [label=\"friends\"];", bNode.GetID(), aNode.GetID()),
+		fmt.Sprintf("\t\"%s\" -> \"%s\" [label=\"enemies\"];", dNode.GetID(), bNode.GetID()),
+		"}",
+		"",
+	}, "\n")
+	assert.Equal(t, expected, buf.String())
+}
+
+func Test_Graph_WriteDOT_FilterLabels(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grf.WriteDOT(&buf, graph.ExportOptions{Labels: []string{puppyType}}))
+	assert.Contains(t, buf.String(), bNode.GetID())
+	assert.NotContains(t, buf.String(), dNode.GetID())
+	assert.Contains(t, buf.String(), "friends")
+	assert.NotContains(t, buf.String(), "enemies")
+}
+
+func Test_Graph_WriteDOT_Highlight(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	chain, err := grf.ShortestPath(context.Background(), dNode, bNode, graph.PathOptions{})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grf.WriteDOT(&buf, graph.ExportOptions{
+		Highlight:      []string{aNode.GetID()},
+		HighlightPaths: []*graph.ChainLink{chain},
+	}))
+	lines := strings.Split(buf.String(), "\n")
+	for _, line := range lines {
+		switch {
+		case strings.Contains(line, "label=\"Azor\""), strings.Contains(line, "label=\"Bobita\""),
+			strings.Contains(line, "label=\"Smaug\""), strings.Contains(line, "enemies"):
+			assert.Contains(t, line, "color=red")
+		case strings.Contains(line, "friends"):
+			assert.NotContains(t, line, "color=red")
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrRelationshipNotAllowed = errors.New("the relationship is not allowed between the two nodes")
+)
+
+// EdgeKind describes a relationship that is allowed between nodes having the given labels
+type EdgeKind struct {
+	FromLabel string
+	Label     string
+	ToLabel   string
+}
+
+// AllowRelationship declares that nodes with the fromLabel can have relationships with the given label to nodes with the toLabel.
+// As long as no edge kind is declared, any relationship is allowed. Once at least one is, relationships that do not match any of the declared kinds are rejected.
+// Only the relationships added afterwards are checked
+func (g *Graph) AllowRelationship(fromLabel, label, toLabel string) {
+	g.Lock()
+	defer g.Unlock()
+	if g.edgeKinds == nil {
+		g.edgeKinds = map[EdgeKind]struct{}{}
+	}
+	g.edgeKinds[EdgeKind{FromLabel: fromLabel, Label: label, ToLabel: toLabel}] = struct{}{}
+}
+
+// EdgeKinds returns the relationships declared with AllowRelationship
+func (g *Graph) EdgeKinds() []EdgeKind {
+	g.RLock()
+	defer g.RUnlock()
+	kinds := make([]EdgeKind, 0, len(g.edgeKinds))
+	for kind := range g.edgeKinds {
+		kinds = append(kinds, kind)
+	}
+	return kinds
+}
+
+// checkEdgeKind returns an error if edge kinds were declared, and none of them matches the relationship. The caller must hold the read lock
+func (g *Graph) checkEdgeKind(from, to Node, label string) error {
+	if len(g.edgeKinds) == 0 {
+		return nil
+	}
+	if _, ok := g.edgeKinds[EdgeKind{FromLabel: from.label, Label: label, ToLabel: to.label}]; ok {
+		return nil
+	}
+	return fmt.Errorf("%w; %s '%s' can not be %s %s '%s'", ErrRelationshipNotAllowed, from.label, from.name, label, to.label, to.name)
+}
@@ -0,0 +1,80 @@
+package graph_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_Graph_RelationshipProperties(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	properties := map[string]string{"since": "2020", "place": "park"}
+	rel, err := grf.AddRelationshipWithProperties(bNode.GetID(), aNode.GetID(), "friends", properties)
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(aNode.GetID(), bNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	// the relationship keeps its own copy of the properties
+	properties["since"] = "2021"
+	stored, err := grf.GetRelationshipByID(rel.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"since": "2020", "place": "park"}, stored.Properties)
+	// and hands out copies of them, which can be changed without changing the stored relationship or the views of the graph
+	view := grf.Snapshot()
+	stored.Properties["since"] = "2022"
+	grf.ListRelationships()[1].Properties["place"] = "home"
+	for _, found := range []*graph.Graph{grf, view} {
+		stored, err = found.GetRelationshipByID(rel.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"since": "2020", "place": "park"}, stored.Properties)
+	}
+
+	assert.Equal(t, []graph.Relationship{rel}, grf.ListRelationships(graph.FilterRelByProperty("since", "2020")))
+	assert.Empty(t, grf.ListRelationships(graph.FilterRelByProperty("since", "2021")))
+	assert.Empty(t, grf.ListRelationships(graph.FilterRelByProperty("owner", "")))
+
+	var buf bytes.Buffer
+	assert.NoError(t, grf.WriteSnapshot(&buf))
+	loaded, err := graph.ReadSnapshot(&buf)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, grf.ListRelationships(), loaded.ListRelationships())
+}
+
+func Test_Graph_AllowRelationship(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+
+	// without declared edge kinds, any relationship is allowed
+	_, err := grf.AddRelationship(dNode.GetID(), bNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	grf.AllowRelationship(puppyType, "friends", puppyType)
+	grf.AllowRelationship(dragonType, "enemies", puppyType)
+	assert.ElementsMatch(t, []graph.EdgeKind{
+		{FromLabel: puppyType, Label: "friends", ToLabel: puppyType},
+		{FromLabel: dragonType, Label: "enemies", ToLabel: puppyType},
+	}, grf.EdgeKinds())
+
+	_, err = grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(dNode.GetID(), aNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(aNode.GetID(), dNode.GetID(), "enemies")
+	assert.ErrorIs(t, err, graph.ErrRelationshipNotAllowed)
+	_, err = grf.AddRelationship(dNode.GetID(), aNode.GetID(), "friends")
+	assert.ErrorIs(t, err, graph.ErrRelationshipNotAllowed)
+
+	err = grf.Batch(func(tx *graph.Tx) error {
+		_, err := tx.AddRelationshipWithProperties(bNode.GetID(), dNode.GetID(), "friends", map[string]string{"since": "never"})
+		return err
+	})
+	assert.ErrorIs(t, err, graph.ErrRelationshipNotAllowed)
+	assert.Len(t, grf.ListRelationships(), 3)
+}
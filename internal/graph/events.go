@@ -0,0 +1,135 @@
+package graph
+
+import (
+	"errors"
+)
+
+// SubscriptionBuffer is the number of events that can wait to be received by a subscriber before it is considered too slow
+const SubscriptionBuffer = 256
+
+var (
+	ErrSlowConsumer = errors.New("the subscriber did not keep up with the changes to the graph")
+)
+
+// EventType identifies the kind of change an event describes
+type EventType int
+
+const (
+	NodeInserted EventType = iota
+	NodeUpdated
+	NodeDeleted
+	RelationshipAdded
+	RelationshipRemoved
+)
+
+func (t EventType) String() string {
+	switch t {
+	case NodeInserted:
+		return "node inserted"
+	case NodeUpdated:
+		return "node updated"
+	case NodeDeleted:
+		return "node deleted"
+	case RelationshipAdded:
+		return "relationship added"
+	case RelationshipRemoved:
+		return "relationship removed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a change made to the graph. Node is set for the node events, and Relationship for the relationship events.
+// For NodeUpdated, Node holds the node after the update, and for the deletions, the element as it was before being removed
+type Event struct {
+	Type         EventType
+	Node         Node
+	Relationship Relationship
+}
+
+// FilterEvents is used to select the events a subscriber receives
+type FilterEvents func(event Event) bool
+
+// FilterEventsByType matches events having one of the given types
+func FilterEventsByType(types ...EventType) FilterEvents {
+	return func(event Event) bool {
+		for _, t := range types {
+			if event.Type == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Subscription delivers the changes made to the graph after it was created
+type Subscription struct {
+	graph  *Graph
+	filter FilterEvents
+	events chan Event
+	err    error
+}
+
+// Subscribe returns a subscription that receives the events matching the filter, or all the events if the filter is nil.
+// Events are delivered in the order the changes were made. Changes made in a batch are only delivered once the batch succeeds.
+// The graph never waits for a subscriber: if more than SubscriptionBuffer events are waiting to be received, the subscription is closed and its Err returns ErrSlowConsumer
+func (g *Graph) Subscribe(filter FilterEvents) *Subscription {
+	g.Lock()
+	defer g.Unlock()
+	sub := &Subscription{
+		graph:  g,
+		filter: filter,
+		events: make(chan Event, SubscriptionBuffer),
+	}
+	if g.subscribers == nil {
+		g.subscribers = map[*Subscription]struct{}{}
+	}
+	g.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Events returns the channel the events are delivered on. It is closed when the subscription ends
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err returns the reason the subscription ended. It is nil while the subscription is active, or if it was ended by Close.
+// It must only be called after the events channel was closed
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Close ends the subscription and closes its events channel. Events that were already delivered can still be received
+func (s *Subscription) Close() {
+	s.graph.Lock()
+	defer s.graph.Unlock()
+	s.graph.unsubscribe(s, nil)
+}
+
+// unsubscribe ends the subscription for the given reason, unless it already ended. The caller must hold the write lock
+func (g *Graph) unsubscribe(sub *Subscription, reason error) {
+	if _, ok := g.subscribers[sub]; !ok {
+		return
+	}
+	delete(g.subscribers, sub)
+	sub.err = reason
+	close(sub.events)
+}
+
+// publish delivers the event to the subscribers, or holds it until the batch in progress succeeds. The caller must hold the write lock
+func (g *Graph) publish(event Event) {
+	if g.batch != nil {
+		g.batch.events = append(g.batch.events, event)
+		return
+	}
+	for sub := range g.subscribers {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			g.unsubscribe(sub, ErrSlowConsumer)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package graph_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+// receive returns the events that were delivered on the subscription so far
+func receive(sub *graph.Subscription) []graph.Event {
+	events := []graph.Event{}
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func eventTypes(events []graph.Event) []graph.EventType {
+	types := []graph.EventType{}
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func Test_Graph_Subscribe(t *testing.T) {
+	grf := graph.New()
+	sub := grf.Subscribe(nil)
+	defer sub.Close()
+
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, err := grf.UpsertNode(azor, puppyType, azorBody)
+	assert.NoError(t, err)
+	rel, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	updated, err := grf.UpsertNode(azor, puppyType, []byte(`{"power":1}`))
+	assert.NoError(t, err)
+	// an empty body does not change the node, so no event is sent for it
+	_, err = grf.UpsertNode(azor, puppyType, []byte{})
+	assert.NoError(t, err)
+	assert.NoError(t, grf.DeleteNode(aNode.GetID(), graph.CascadeRelationships))
+
+	events := receive(sub)
+	assert.Equal(t, []graph.EventType{
+		graph.NodeInserted,
+		graph.NodeInserted,
+		graph.RelationshipAdded,
+		graph.NodeUpdated,
+		graph.RelationshipRemoved,
+		graph.NodeDeleted,
+	}, eventTypes(events))
+	assert.Equal(t, bNode, events[0].Node)
+	assert.Equal(t, rel, events[2].Relationship)
+	assert.Equal(t, updated, events[3].Node)
+	assert.Equal(t, rel, events[4].Relationship)
+	assert.Equal(t, updated, events[5].Node)
+}
+
+func Test_Graph_Subscribe_Filter(t *testing.T) {
+	grf := graph.New()
+	sub := grf.Subscribe(graph.FilterEventsByType(graph.RelationshipAdded, graph.RelationshipRemoved))
+	defer sub.Close()
+
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	rel, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	assert.NoError(t, grf.DeleteRelationship(rel.ID))
+
+	assert.Equal(t, []graph.EventType{graph.RelationshipAdded, graph.RelationshipRemoved}, eventTypes(receive(sub)))
+}
+
+func Test_Graph_Subscribe_Batch(t *testing.T) {
+	grf := graph.New()
+	sub := grf.Subscribe(nil)
+	defer sub.Close()
+
+	err := grf.Batch(func(tx *graph.Tx) error {
+		tx.InsertNode(bobita, puppyType, bobitaBody)
+		// nothing is delivered before the batch succeeds
+		assert.Empty(t, receive(sub))
+		return errors.New("failed")
+	})
+	assert.Error(t, err)
+	assert.Empty(t, receive(sub))
+
+	err = grf.Batch(func(tx *graph.Tx) error {
+		bNode, _ := tx.InsertNode(bobita, puppyType, bobitaBody)
+		aNode, _ := tx.InsertNode(azor, puppyType, azorBody)
+		_, err := tx.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []graph.EventType{graph.NodeInserted, graph.NodeInserted, graph.RelationshipAdded}, eventTypes(receive(sub)))
+}
+
+func Test_Graph_Subscribe_SlowConsumer(t *testing.T) {
+	grf := graph.New()
+	slow := grf.Subscribe(nil)
+	fast := grf.Subscribe(nil)
+	defer fast.Close()
+
+	for i := 0; i <= graph.SubscriptionBuffer; i++ {
+		grf.InsertNode(bobita, puppyType, bobitaBody)
+		<-fast.Events()
+	}
+
+	// the events that fit in the buffer can still be received, after which the channel is closed
+	assert.Len(t, receive(slow), graph.SubscriptionBuffer)
+	_, ok := <-slow.Events()
+	assert.False(t, ok)
+	assert.ErrorIs(t, slow.Err(), graph.ErrSlowConsumer)
+
+	grf.InsertNode(azor, puppyType, azorBody)
+	assert.Len(t, receive(fast), 1)
+}
+
+func Test_Graph_Subscribe_Close(t *testing.T) {
+	grf := graph.New()
+	sub := grf.Subscribe(nil)
+	sub.Close()
+	sub.Close()
+	grf.InsertNode(bobita, puppyType, bobitaBody)
+
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	assert.NoError(t, sub.Err())
+}
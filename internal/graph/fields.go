@@ -0,0 +1,162 @@
+package graph
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// FieldSeparator separates the names of nested fields in the path to a field of a json body
+const FieldSeparator = "."
+
+// fieldKey identifies an indexed field: the path to it in the bodies of the nodes having the label
+type fieldKey struct {
+	label string
+	path  string
+}
+
+// index returns the name of the index of the field in the store
+func (k fieldKey) index() string {
+	return fieldIndexPrefix + k.label + ":" + k.path
+}
+
+// FilterNodesByField matches nodes whose json body holds the value at the given path, e.g.: FilterNodesByField("direction", "inbound").
+// Nested fields are separated by dots. If the field is a list, the node matches if the list contains the value.
+// Values are compared by their json encoding, so the value should be a string, number, bool or nil.
+// When passed to ListNodes, the index declared with IndexField is used for the labels that have one, instead of decoding every body
+func FilterNodesByField(path string, value interface{}) IndexFilter {
+	return IndexFilter{Index: FieldIndex, Path: path, Keys: []string{fieldValueKey(value)}}
+}
+
+// IndexField declares that the nodes having the label are indexed by the value found at the path in their json body, e.g.: IndexField("securityGroup", "direction").
+// Nested fields are separated by dots, and lists are indexed by each of their items. The nodes already in the graph are indexed right away,
+// and the index is kept up to date as nodes are added, updated and deleted. Views can not be indexed, which is reported by Err
+func (g *Graph) IndexField(label, path string) {
+	g.Lock()
+	defer g.Unlock()
+	if g.readOnly {
+		// the field is not declared either, since lookups would go to an index the view does not have
+		if g.err == nil {
+			g.err = ErrReadOnly
+		}
+		return
+	}
+	key := fieldKey{label: label, path: path}
+	if g.fields == nil {
+		g.fields = map[fieldKey]struct{}{}
+	}
+	if _, ok := g.fields[key]; ok {
+		return
+	}
+	for _, id := range g.store.Lookup(labelIndexName, label) {
+		for _, value := range fieldKeys(g.node(id).Body, path) {
+			g.store.Index(key.index(), value, id)
+		}
+	}
+	g.fields[key] = struct{}{}
+}
+
+// IndexedFields returns the fields indexed for the label, declared with IndexField
+func (g *Graph) IndexedFields(label string) []string {
+	g.RLock()
+	defer g.RUnlock()
+	paths := []string{}
+	for key := range g.fields {
+		if key.label == label {
+			paths = append(paths, key.path)
+		}
+	}
+	return paths
+}
+
+// indexFields adds the node to the field indexes declared for its label. The caller must hold the write lock
+func (g *Graph) indexFields(node Node) {
+	for key := range g.fields {
+		if key.label != node.label {
+			continue
+		}
+		for _, value := range fieldKeys(node.Body, key.path) {
+			g.store.Index(key.index(), value, node.id)
+		}
+	}
+}
+
+// unindexFields removes the node from the field indexes declared for its label. The caller must hold the write lock
+func (g *Graph) unindexFields(node Node) {
+	for key := range g.fields {
+		if key.label != node.label {
+			continue
+		}
+		for _, value := range fieldKeys(node.Body, key.path) {
+			g.store.Unindex(key.index(), value, node.id)
+		}
+	}
+}
+
+// lookupField returns the IDs of the nodes that could have one of the values at the path. Nodes having a label without an index for the path
+// are all returned, since their bodies need to be checked. The caller must hold the read lock
+func (g *Graph) lookupField(path string, values []string) []string {
+	ids := []string{}
+	for _, label := range g.store.Keys(labelIndexName) {
+		key := fieldKey{label: label, path: path}
+		if _, ok := g.fields[key]; ok {
+			ids = append(ids, g.store.Lookup(key.index(), values...)...)
+			continue
+		}
+		ids = append(ids, g.store.Lookup(labelIndexName, label)...)
+	}
+	return ids
+}
+
+// matchesField checks a field filter against the index, if the label of the node has one for the path. ok is false if the body needs to be checked instead.
+// The caller must hold the read lock
+func (g *Graph) matchesField(node Node, filter IndexFilter) (matches, ok bool) {
+	key := fieldKey{label: node.label, path: filter.Path}
+	if _, ok := g.fields[key]; !ok {
+		return false, false
+	}
+	for _, value := range filter.Keys {
+		if g.store.Indexed(key.index(), value, node.id) {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// fieldKeys returns the json encoding of the value found at the path in the body, or of each item if the value is a list.
+// Nothing is returned if the body is not a json object holding the path
+func fieldKeys(body []byte, path string) []string {
+	if len(body) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil
+	}
+	for _, field := range strings.Split(path, FieldSeparator) {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if value, ok = object[field]; !ok {
+			return nil
+		}
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return []string{fieldValueKey(value)}
+	}
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, fieldValueKey(item))
+	}
+	return keys
+}
+
+// fieldValueKey encodes the value as json, so that values decoded from bodies and values given to filters can be compared
+func fieldValueKey(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
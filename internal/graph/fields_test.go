@@ -0,0 +1,72 @@
+package graph_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_Graph_FilterNodesByField(t *testing.T) {
+	grf := graph.New()
+	grf.InsertNode("sg1", "securityGroup", []byte(`{"direction":"inbound","ports":[80,443],"rule":{"priority":10}}`))
+	grf.InsertNode("sg2", "securityGroup", []byte(`{"direction":"outbound","ports":[22],"rule":{"priority":20}}`))
+	grf.InsertNode("sg3", "securityGroup", []byte{})
+	grf.InsertNode("vm1", "vm", []byte(`{"direction":"inbound"}`))
+
+	names := func(nodes []graph.Node) []string {
+		found := []string{}
+		for _, node := range nodes {
+			found = append(found, node.GetName())
+		}
+		return found
+	}
+	check := func() {
+		assert.ElementsMatch(t, []string{"sg1", "vm1"}, names(grf.ListNodes(graph.FilterNodesByField("direction", "inbound"))))
+		assert.ElementsMatch(t, []string{"sg1"}, names(grf.ListNodes(graph.FilterNodesByField("direction", "inbound"), graph.FilterNodesByLabel("securityGroup"))))
+		assert.ElementsMatch(t, []string{"sg1"}, names(grf.ListNodes(graph.FilterNodesByField("ports", 443))))
+		assert.ElementsMatch(t, []string{"sg2"}, names(grf.ListNodes(graph.FilterNodesByField("rule.priority", 20))))
+		assert.Empty(t, grf.ListNodes(graph.FilterNodesByField("direction", "sideways")))
+	}
+	check()
+
+	// the index gives the same results as decoding the bodies
+	grf.IndexField("securityGroup", "direction")
+	grf.IndexField("securityGroup", "ports")
+	grf.IndexField("securityGroup", "rule.priority")
+	assert.ElementsMatch(t, []string{"direction", "ports", "rule.priority"}, grf.IndexedFields("securityGroup"))
+	assert.Empty(t, grf.IndexedFields("vm"))
+	check()
+}
+
+func Test_Graph_IndexField_KeptUpToDate(t *testing.T) {
+	grf := graph.New()
+	grf.IndexField("securityGroup", "direction")
+	inbound := graph.FilterNodesByField("direction", "inbound")
+
+	sg, err := grf.UpsertNode("sg1", "securityGroup", []byte(`{"direction":"inbound"}`))
+	assert.NoError(t, err)
+	assert.Len(t, grf.ListNodes(inbound), 1)
+
+	_, err = grf.UpsertNode("sg1", "securityGroup", []byte(`{"direction":"outbound"}`))
+	assert.NoError(t, err)
+	assert.Empty(t, grf.ListNodes(inbound))
+	assert.Len(t, grf.ListNodes(graph.FilterNodesByField("direction", "outbound")), 1)
+
+	// a rolled back update restores the previous value in the index
+	err = grf.Batch(func(tx *graph.Tx) error {
+		if _, err := tx.UpsertNode("sg1", "securityGroup", []byte(`{"direction":"inbound"}`)); err != nil {
+			return err
+		}
+		assert.Len(t, tx.ListNodes(inbound), 1)
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+	assert.Empty(t, grf.ListNodes(inbound))
+	assert.Len(t, grf.ListNodes(graph.FilterNodesByField("direction", "outbound")), 1)
+
+	assert.NoError(t, grf.DeleteNode(sg.GetID(), graph.RejectDangling))
+	assert.Empty(t, grf.ListNodes(graph.FilterNodesByField("direction", "outbound")))
+}
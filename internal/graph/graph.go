@@ -1,39 +1,104 @@
 package graph
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"reflect"
+	"sort"
 	"sync"
 )
 
 var (
-	ErrNotFound = errors.New("could not find an element matching the request")
+	ErrNotFound         = errors.New("could not find an element matching the request")
+	ErrHasRelationships = errors.New("the node still has relationships")
+	ErrAmbiguousNode    = errors.New("more than one node matches the request")
+	ErrReadOnly         = errors.New("the graph is a read-only view")
 )
 
+// DeleteMode specifies what happens with the relationships of a node when it is deleted
+type DeleteMode int
+
+const (
+	// RejectDangling refuses to delete a node that still has relationships
+	RejectDangling DeleteMode = iota
+	// CascadeRelationships deletes all the relationships starting from or pointing to the node, along with the node
+	CascadeRelationships
+)
+
+// NodeFilter decides if a node is part of the result of a query. Filters are either plain FilterNodes functions, checked against every node,
+// or an IndexFilter, which can be answered from the indexes of the graph
+type NodeFilter interface {
+	Match(node Node) bool
+}
+
 // FilterNodes is used as an interface for filtering functionality. This allows each user to provide their own way of filtering different items
 type FilterNodes func(node Node) bool
 
-func FilterNodesByLabel(labels ...string) FilterNodes {
-	return func(node Node) bool {
-		for _, label := range labels {
-			if node.GetLabel() == label {
-				return true
-			}
-		}
-		return false
-	}
+// Match calls the filter function
+func (f FilterNodes) Match(node Node) bool {
+	return f(node)
+}
+
+// IndexKind names the index an IndexFilter is looked up in
+type IndexKind int
+
+const (
+	// LabelIndex matches nodes by their label
+	LabelIndex IndexKind = iota
+	// NameIndex matches nodes by their name
+	NameIndex
+	// NamespaceIndex matches nodes by their namespace
+	NamespaceIndex
+	// FieldIndex matches nodes by the value found at Path in their json body, as FilterNodesByField does
+	FieldIndex
+)
+
+// IndexFilter matches nodes having one of the keys in the index. When passed to ListNodes, only the nodes found in the index are checked.
+// For the field index, Path is the path to the field and the keys are json encoded values
+type IndexFilter struct {
+	Index IndexKind
+	Keys  []string
+	Path  string
 }
 
-func FilterNodesByName(names ...string) FilterNodes {
-	return func(node Node) bool {
-		for _, name := range names {
-			if node.GetName() == name {
+// Match checks the node itself, without using the index
+func (f IndexFilter) Match(node Node) bool {
+	var found []string
+	switch f.Index {
+	case LabelIndex:
+		found = []string{node.GetLabel()}
+	case NameIndex:
+		found = []string{node.GetName()}
+	case NamespaceIndex:
+		found = []string{node.GetNamespace()}
+	case FieldIndex:
+		found = fieldKeys(node.Body, f.Path)
+	}
+	for _, value := range found {
+		for _, key := range f.Keys {
+			if value == key {
 				return true
 			}
 		}
-		return false
 	}
+	return false
+}
+
+// FilterNodesByLabel matches nodes having one of the given labels, using the label index
+func FilterNodesByLabel(labels ...string) IndexFilter {
+	return IndexFilter{Index: LabelIndex, Keys: labels}
+}
+
+// FilterNodesByNamespace matches nodes in one of the given namespaces, using the namespace index
+func FilterNodesByNamespace(namespaces ...string) IndexFilter {
+	return IndexFilter{Index: NamespaceIndex, Keys: namespaces}
+}
+
+// FilterNodesByName matches nodes having one of the given names, using the name index
+func FilterNodesByName(names ...string) IndexFilter {
+	return IndexFilter{Index: NameIndex, Keys: names}
 }
 
 type FilterRelationship func(rel Relationship) bool
@@ -56,164 +121,527 @@ func FilterRelByFrom(fromID string) FilterRelationship {
 	}
 }
 
+// FilterRelByProperty matches relationships where the property has the given value
+func FilterRelByProperty(key, value string) FilterRelationship {
+	return func(rel Relationship) bool {
+		v, ok := rel.Properties[key]
+		return ok && v == value
+	}
+}
+
+// New creates an empty graph, kept in memory
 func New() *Graph {
-	return &Graph{
-		nodes:         map[string]Node{},
-		relationships: map[string]Relationship{},
+	return &Graph{store: NewMemoryStore()}
+}
+
+// NewWithStore creates a graph kept in the given store. If the store already holds nodes and relationships, e.g. a file written by a previous run, they are indexed again
+func NewWithStore(store Store) (*Graph, error) {
+	g := &Graph{store: store}
+	store.ForEachNode(func(node Node) bool {
+		g.indexNode(node)
+		return true
+	})
+	store.ForEachRelationship(func(rel Relationship) bool {
+		g.indexRelationship(rel)
+		return true
+	})
+	if err := store.Err(); err != nil {
+		return nil, fmt.Errorf("could not read the store; %w", err)
 	}
+	return g, nil
 }
 
 // Graph represents a collection of different nodes of the same type
 type Graph struct {
 	sync.RWMutex
-	nodes         map[string]Node
-	relationships map[string]Relationship
+	// store holds the nodes and relationships, indexed by the name, label and namespace of the nodes, and by the nodes each relationship starts from and points to
+	store Store
+	// subscribers receive the changes made to the graph, and batch holds the transaction in progress, whose changes are only published once it succeeds
+	subscribers map[*Subscription]struct{}
+	batch       *Tx
+	// bodyTypes holds the Go types registered for the bodies of the nodes, by label
+	bodyTypes map[string]reflect.Type
+	// edgeKinds holds the relationships allowed between nodes; if empty, any relationship is allowed
+	edgeKinds map[EdgeKind]struct{}
+	// fields holds the fields of the bodies declared with IndexField
+	fields map[fieldKey]struct{}
+	// readOnly is set for the views returned by Snapshot
+	readOnly bool
+	// err holds the first change made through a view that could not return an error, like IndexField
+	err error
 }
 
-// InsertNode adds a new node to the graph
-func (g *Graph) InsertNode(name, label string, body []byte) Node {
+// Snapshot returns a read-only view of the graph as it is now, e.g.: to run several checks against the same version of the graph while it keeps being changed.
+// It is unrelated to the snapshot files written by WriteSnapshot.
+// Changes made to the graph afterwards are not seen through the view, and readers of the view do not hold up writers of the graph.
+// Taking a view does not copy anything, and the changes made to the graph afterwards only copy the few parts of the store they touch that are shared with the view.
+// Changes made through the view fail with ErrReadOnly; the ones that can not return an error, like IndexField, are reported by Err
+func (g *Graph) Snapshot() *Graph {
 	g.Lock()
 	defer g.Unlock()
-	node := newNode(name, label, body)
-	g.nodes[node.id] = node
-	return node
+	view := &Graph{
+		store:     g.store.View(),
+		bodyTypes: make(map[string]reflect.Type, len(g.bodyTypes)),
+		edgeKinds: make(map[EdgeKind]struct{}, len(g.edgeKinds)),
+		fields:    make(map[fieldKey]struct{}, len(g.fields)),
+		readOnly:  true,
+	}
+	for label, t := range g.bodyTypes {
+		view.bodyTypes[label] = t
+	}
+	for kind := range g.edgeKinds {
+		view.edgeKinds[kind] = struct{}{}
+	}
+	for key := range g.fields {
+		view.fields[key] = struct{}{}
+	}
+	return view
+}
+
+// Err returns the first error the store of the graph ran into. Reads that failed returned nothing, so results produced since then might be incomplete.
+// Graphs kept in memory only fail when changed through a view
+func (g *Graph) Err() error {
+	g.RLock()
+	defer g.RUnlock()
+	if g.err != nil {
+		return g.err
+	}
+	return g.store.Err()
+}
+
+// Close releases the store of the graph, and with it the views taken from it. The graph can not be used afterwards; closing a view does nothing
+func (g *Graph) Close() error {
+	g.Lock()
+	defer g.Unlock()
+	return g.store.Close()
+}
+
+// InsertNode adds a new node to the graph. If a body type was registered for the label, and the body does not match it, the node is not added and ErrInvalidBody is returned
+func (g *Graph) InsertNode(name, label string, body []byte) (Node, error) {
+	if g.readOnly {
+		return Node{}, ErrReadOnly
+	}
+	g.Lock()
+	defer g.Unlock()
+	return g.insertNode("", name, label, body)
+}
+
+// insertNode checks the body of a new node against the type registered for the label, then stores the node. The caller must hold the write lock
+func (g *Graph) insertNode(namespace, name, label string, body []byte) (Node, error) {
+	node := newNode(namespace, name, label, body)
+	if err := g.checkBody(node); err != nil {
+		return Node{}, err
+	}
+	g.putNode(node)
+	g.publish(Event{Type: NodeInserted, Node: node})
+	return node, nil
+}
+
+// UpsertNode treats the name and label as a unique key for the node in the default namespace. If no node exists for the key, a new one is inserted.
+// Otherwise, the body of the existing node is replaced, keeping its ID and relationships; an empty body leaves the existing one unchanged, e.g.: to reference a node before it is loaded.
+// If a body type was registered for the label, and the resulting body does not match it, the node is left unchanged and ErrInvalidBody is returned
+func (g *Graph) UpsertNode(name, label string, body []byte) (Node, error) {
+	return g.UpsertNodeInNamespace("", name, label, body)
+}
+
+// UpsertNodeInNamespace works like UpsertNode, with the namespace being part of the unique key of the node.
+// Namespaces keep apart nodes that have the same name and label, e.g.: assets with the same ID in different accounts
+func (g *Graph) UpsertNodeInNamespace(namespace, name, label string, body []byte) (Node, error) {
+	if g.readOnly {
+		return Node{}, ErrReadOnly
+	}
+	g.Lock()
+	defer g.Unlock()
+	return g.upsertNode(namespace, name, label, body)
+}
+
+// upsertNode inserts or updates the node with the given namespace, name and label, as described by UpsertNode. The caller must hold the write lock
+func (g *Graph) upsertNode(namespace, name, label string, body []byte) (Node, error) {
+	existing := []Node{}
+	for _, id := range g.store.Lookup(nameIndexName, name) {
+		if node, ok := g.store.GetNode(id); ok && node.label == label && node.namespace == namespace {
+			existing = append(existing, node)
+		}
+	}
+	switch len(existing) {
+	case 0:
+		return g.insertNode(namespace, name, label, body)
+	case 1:
+		node := existing[0]
+		if len(body) == 0 || bytes.Equal(body, node.Body) {
+			return node, nil
+		}
+		node.Body = body
+		if err := g.checkBody(node); err != nil {
+			return Node{}, err
+		}
+		g.replaceNode(node)
+		g.publish(Event{Type: NodeUpdated, Node: node})
+		return node, nil
+	default:
+		return Node{}, fmt.Errorf("%w; found %d nodes with name '%s' and label '%s' in namespace '%s'", ErrAmbiguousNode, len(existing), name, label, namespace)
+	}
+}
+
+// putNode stores the node and adds it to the indexes. The caller must hold the write lock
+func (g *Graph) putNode(node Node) {
+	g.store.PutNode(node)
+	g.indexNode(node)
+}
+
+// indexNode adds the node to the indexes. The caller must hold the write lock
+func (g *Graph) indexNode(node Node) {
+	g.store.Index(nameIndexName, node.name, node.id)
+	g.store.Index(labelIndexName, node.label, node.id)
+	g.store.Index(namespaceIndexName, node.namespace, node.id)
+	g.indexFields(node)
+}
+
+// replaceNode stores a new version of a node already in the graph, having the same name, label and namespace. The caller must hold the write lock
+func (g *Graph) replaceNode(node Node) {
+	if old, ok := g.store.GetNode(node.id); ok {
+		g.unindexFields(old)
+	}
+	g.store.PutNode(node)
+	g.indexFields(node)
 }
 
 // GetNodeByID returns the node that has the given ID
 func (g *Graph) GetNodeByID(id string) (Node, error) {
 	g.RLock()
 	defer g.RUnlock()
-	item, ok := g.nodes[id]
+	return g.getNodeByID(id)
+}
+
+// node returns the node that has the given ID, or an empty node if there is none. The caller must hold the read lock
+func (g *Graph) node(id string) Node {
+	node, _ := g.store.GetNode(id)
+	return node
+}
+
+// getNodeByID returns the node that has the given ID. The caller must hold the read lock
+func (g *Graph) getNodeByID(id string) (Node, error) {
+	item, ok := g.store.GetNode(id)
 	if !ok {
 		return Node{}, fmt.Errorf("%w; node with id '%s'", ErrNotFound, id)
 	}
 	return item, nil
 }
 
-// ListNodes returns a map of all the nodes that match all the where clauses provided.
-func (g *Graph) ListNodes(where ...FilterNodes) []Node {
+// ListNodes returns all the nodes that match all the where clauses provided, sorted by label, then name, namespace and ID.
+// If any of the clauses is an IndexFilter, such as FilterNodesByName, FilterNodesByLabel, FilterNodesByNamespace or FilterNodesByField, only the nodes found in the matching index are checked
+func (g *Graph) ListNodes(where ...NodeFilter) []Node {
 	g.RLock()
 	defer g.RUnlock()
-	matchingNodes := make([]Node, 0, len(g.nodes))
-	for _, item := range g.nodes {
-		matches := true
-		for _, clause := range where {
-			if ok := clause(item); !ok {
-				matches = false
-				break
+	return g.listNodes(where)
+}
+
+// listNodes returns the nodes matching all the where clauses, sorted. The caller must hold the read lock
+func (g *Graph) listNodes(where []NodeFilter) []Node {
+	candidates, indexed := g.candidates(where)
+	if !indexed {
+		matchingNodes := make([]Node, 0, g.store.CountNodes())
+		g.store.ForEachNode(func(item Node) bool {
+			if g.matchesAll(item, where) {
+				matchingNodes = append(matchingNodes, item)
 			}
-		}
-		if matches {
+			return true
+		})
+		sortNodes(matchingNodes)
+		return matchingNodes
+	}
+	matchingNodes := make([]Node, 0, len(candidates))
+	for _, id := range candidates {
+		item, ok := g.store.GetNode(id)
+		if ok && g.matchesAll(item, where) {
 			matchingNodes = append(matchingNodes, item)
 		}
 	}
-
+	sortNodes(matchingNodes)
 	return matchingNodes
 }
 
+// lessNode orders nodes by label, then name, namespace and ID
+func lessNode(a, b Node) bool {
+	if a.label != b.label {
+		return a.label < b.label
+	}
+	if a.name != b.name {
+		return a.name < b.name
+	}
+	if a.namespace != b.namespace {
+		return a.namespace < b.namespace
+	}
+	return a.id < b.id
+}
+
+func sortNodes(nodes []Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return lessNode(nodes[i], nodes[j])
+	})
+}
+
+// matchesAll checks the node against all the clauses. Field filters are checked against the index when the label of the node has one, instead of decoding the body.
+// The caller must hold the read lock
+func (g *Graph) matchesAll(node Node, where []NodeFilter) bool {
+	for _, clause := range where {
+		if filter, ok := clause.(IndexFilter); ok && filter.Index == FieldIndex {
+			if matches, ok := g.matchesField(node, filter); ok {
+				if !matches {
+					return false
+				}
+				continue
+			}
+		}
+		if ok := clause.Match(node); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// candidates uses the indexes to find the IDs of the nodes that could match the clauses that are an IndexFilter. The smallest set found is returned.
+// If none of the clauses can use an index, indexed is false and all the nodes need to be checked. The caller must hold the read lock
+func (g *Graph) candidates(where []NodeFilter) (ids []string, indexed bool) {
+	for _, clause := range where {
+		filter, ok := clause.(IndexFilter)
+		if !ok {
+			continue
+		}
+		var found []string
+		switch filter.Index {
+		case LabelIndex:
+			found = g.store.Lookup(labelIndexName, filter.Keys...)
+		case NameIndex:
+			found = g.store.Lookup(nameIndexName, filter.Keys...)
+		case NamespaceIndex:
+			found = g.store.Lookup(namespaceIndexName, filter.Keys...)
+		case FieldIndex:
+			found = g.lookupField(filter.Path, filter.Keys)
+		default:
+			continue
+		}
+		if !indexed || len(found) < len(ids) {
+			ids = found
+			indexed = true
+		}
+	}
+	return ids, indexed
+}
+
+// DeleteNode removes the node from the graph. The mode decides if the node is removed along with its relationships, or if the deletion is rejected when it has any
+func (g *Graph) DeleteNode(id string, mode DeleteMode) error {
+	if g.readOnly {
+		return ErrReadOnly
+	}
+	g.Lock()
+	defer g.Unlock()
+	return g.deleteNode(id, mode)
+}
+
+// deleteNode removes the node, and its relationships if the mode allows it. The caller must hold the write lock
+func (g *Graph) deleteNode(id string, mode DeleteMode) error {
+	node, ok := g.store.GetNode(id)
+	if !ok {
+		return fmt.Errorf("%w; node with id '%s'", ErrNotFound, id)
+	}
+	dangling := g.store.Count(outgoingIndexName, id) + g.store.Count(incomingIndexName, id)
+	if dangling > 0 && mode != CascadeRelationships {
+		return fmt.Errorf("%w; node '%s' has %d relationships", ErrHasRelationships, node.name, dangling)
+	}
+	for _, rel := range append(g.adjacent(outgoingIndexName, id, nil), g.adjacent(incomingIndexName, id, nil)...) {
+		// a relationship from the node to itself is both outgoing and incoming
+		if _, ok := g.store.GetRelationship(rel.ID); !ok {
+			continue
+		}
+		g.publish(Event{Type: RelationshipRemoved, Relationship: rel})
+		g.removeRelationship(rel.ID)
+	}
+	g.removeNode(id)
+	g.publish(Event{Type: NodeDeleted, Node: node})
+	return nil
+}
+
+// removeNode deletes the node and removes it from the indexes. The caller must hold the write lock
+func (g *Graph) removeNode(id string) {
+	node, ok := g.store.GetNode(id)
+	if !ok {
+		return
+	}
+	g.store.DeleteNode(id)
+	g.store.Unindex(nameIndexName, node.name, id)
+	g.store.Unindex(labelIndexName, node.label, id)
+	g.store.Unindex(namespaceIndexName, node.namespace, id)
+	g.unindexFields(node)
+}
+
 // AddRelationship is used to establish a unidirectional relationship between the two items in the graph
 func (g *Graph) AddRelationship(fromID, toID, label string) (Relationship, error) {
-	fromNode, err := g.GetNodeByID(fromID)
-	if err != nil {
-		return Relationship{}, fmt.Errorf("getNodeByID %s; %w", fromID, err)
-	}
+	return g.AddRelationshipWithProperties(fromID, toID, label, nil)
+}
 
-	toNode, err := g.GetNodeByID(toID)
-	if err != nil {
-		return Relationship{}, fmt.Errorf("getNodeByID %s; %w", fromID, err)
+// AddRelationshipWithProperties establishes a unidirectional relationship between the two items in the graph, holding a copy of the given properties.
+// If edge kinds were declared with AllowRelationship, the relationship must match one of them, otherwise ErrRelationshipNotAllowed is returned
+func (g *Graph) AddRelationshipWithProperties(fromID, toID, label string, properties map[string]string) (Relationship, error) {
+	if g.readOnly {
+		return Relationship{}, ErrReadOnly
 	}
 	g.Lock()
 	defer g.Unlock()
-	rel := newRelationship(fromNode, toNode, label)
-	g.relationships[rel.ID] = rel
+	return g.addRelationship(fromID, toID, label, properties)
+}
+
+// addRelationship creates a relationship between the two nodes, after checking that both exist and that the relationship is allowed. The caller must hold the write lock
+func (g *Graph) addRelationship(fromID, toID, label string, properties map[string]string) (Relationship, error) {
+	fromNode, ok := g.store.GetNode(fromID)
+	if !ok {
+		return Relationship{}, fmt.Errorf("getNodeByID %s; %w; node with id '%s'", fromID, ErrNotFound, fromID)
+	}
+	toNode, ok := g.store.GetNode(toID)
+	if !ok {
+		return Relationship{}, fmt.Errorf("getNodeByID %s; %w; node with id '%s'", toID, ErrNotFound, toID)
+	}
+	if err := g.checkEdgeKind(fromNode, toNode, label); err != nil {
+		return Relationship{}, err
+	}
+	rel := newRelationship(fromNode, toNode, label, properties)
+	g.putRelationship(rel)
+	g.publish(Event{Type: RelationshipAdded, Relationship: rel})
 
 	return rel, nil
 }
 
-func (g *Graph) GetRelationshipByID(id string) (Relationship, error) {
-	g.RLock()
-	defer g.RUnlock()
-	item, ok := g.relationships[id]
+// putRelationship stores the relationship and adds it to the adjacency indexes. The caller must hold the write lock
+func (g *Graph) putRelationship(rel Relationship) {
+	g.store.PutRelationship(rel)
+	g.indexRelationship(rel)
+}
+
+// indexRelationship adds the relationship to the adjacency indexes. The caller must hold the write lock
+func (g *Graph) indexRelationship(rel Relationship) {
+	g.store.Index(outgoingIndexName, rel.From, rel.ID)
+	g.store.Index(incomingIndexName, rel.To, rel.ID)
+}
+
+// DeleteRelationship removes the relationship from the graph. The nodes it connects are not changed
+func (g *Graph) DeleteRelationship(id string) error {
+	if g.readOnly {
+		return ErrReadOnly
+	}
+	g.Lock()
+	defer g.Unlock()
+	return g.deleteRelationship(id)
+}
+
+// deleteRelationship removes the relationship, after checking that it exists. The caller must hold the write lock
+func (g *Graph) deleteRelationship(id string) error {
+	rel, ok := g.store.GetRelationship(id)
 	if !ok {
-		return Relationship{}, fmt.Errorf("%w; relationship with id '%s'", ErrNotFound, id)
+		return fmt.Errorf("%w; relationship with id '%s'", ErrNotFound, id)
 	}
-	return item, nil
+	g.removeRelationship(id)
+	g.publish(Event{Type: RelationshipRemoved, Relationship: rel})
+	return nil
 }
 
-func (g *Graph) ListRelationships(filters ...FilterRelationship) []Relationship {
-	g.RLock()
-	defer g.RUnlock()
-	matchingRelationships := make([]Relationship, 0, len(g.relationships))
-	for _, item := range g.relationships {
-		matches := true
-		for _, clause := range filters {
-			if ok := clause(item); !ok {
-				matches = false
-				break
-			}
-		}
-		if matches {
-			matchingRelationships = append(matchingRelationships, item)
-		}
+// removeRelationship deletes the relationship and removes it from the adjacency indexes. The caller must hold the write lock
+func (g *Graph) removeRelationship(id string) {
+	rel, ok := g.store.GetRelationship(id)
+	if !ok {
+		return
 	}
+	g.store.DeleteRelationship(id)
+	g.store.Unindex(outgoingIndexName, rel.From, id)
+	g.store.Unindex(incomingIndexName, rel.To, id)
+}
 
-	return matchingRelationships
+// Outgoing returns the relationships starting from the given node, sorted as by ListRelationships. If labels are provided, only the relationships having one of the labels are returned
+func (g *Graph) Outgoing(nodeID string, labels ...string) []Relationship {
+	g.RLock()
+	defer g.RUnlock()
+	return g.adjacent(outgoingIndexName, nodeID, labels)
 }
 
-func (g *Graph) ListConnections(from, to Node) []*ChainLink {
-	return g.listConnections(from, to, map[string]struct{}{})
+// Incoming returns the relationships pointing to the given node, sorted as by ListRelationships. If labels are provided, only the relationships having one of the labels are returned
+func (g *Graph) Incoming(nodeID string, labels ...string) []Relationship {
+	g.RLock()
+	defer g.RUnlock()
+	return g.adjacent(incomingIndexName, nodeID, labels)
 }
 
-func (g *Graph) listConnections(from, to Node, visited map[string]struct{}) []*ChainLink {
-	chains := []*ChainLink{}
-	visited[from.id] = struct{}{}
-	for _, rel := range g.ListRelationships(FilterRelByFrom(from.GetID())) {
-		toCheck := copyMap(visited)
-		// check if the relationship has already been visited. If it has, then go to the next one
-		if _, ok := visited[rel.To]; ok {
-			continue
-		}
-		toCheck[rel.To] = struct{}{}
-		if rel.To == to.id {
-			chains = append(chains, &ChainLink{node: from, rel: rel, next: &ChainLink{node: to}})
-			continue
-		}
-		next, ok := g.nodes[rel.To]
-		if !ok {
-			continue
+// adjacent returns the relationships indexed for the node in the given adjacency index, sorted, so that graph walks follow them in the same order every time.
+// The caller must hold the read lock
+func (g *Graph) adjacent(index, nodeID string, labels []string) []Relationship {
+	ids := g.store.Lookup(index, nodeID)
+	rels := make([]Relationship, 0, len(ids))
+	for _, id := range ids {
+		rel, ok := g.store.GetRelationship(id)
+		if ok && (len(labels) == 0 || hasLabel(rel, labels)) {
+			rels = append(rels, rel)
 		}
-		connections := g.listConnections(next, to, toCheck)
-		for _, cons := range connections {
-			chains = append(chains, &ChainLink{node: from, rel: rel, next: cons})
+	}
+	sortRelationships(rels)
+	return rels
+}
+
+func hasLabel(rel Relationship, labels []string) bool {
+	for _, label := range labels {
+		if rel.Label == label {
+			return true
 		}
 	}
-	return chains
+	return false
 }
 
-func copyMap(m map[string]struct{}) map[string]struct{} {
-	n := map[string]struct{}{}
-	for k, v := range m {
-		n[k] = v
+func (g *Graph) GetRelationshipByID(id string) (Relationship, error) {
+	g.RLock()
+	defer g.RUnlock()
+	item, ok := g.store.GetRelationship(id)
+	if !ok {
+		return Relationship{}, fmt.Errorf("%w; relationship with id '%s'", ErrNotFound, id)
 	}
-	return n
+	return item, nil
 }
 
-type ChainLink struct {
-	node Node
-	rel  Relationship
-	next *ChainLink
+// ListRelationships returns all the relationships that match all the filters, sorted by the names of the nodes they start from and point to, then label and ID
+func (g *Graph) ListRelationships(filters ...FilterRelationship) []Relationship {
+	g.RLock()
+	defer g.RUnlock()
+	matchingRelationships := make([]Relationship, 0, g.store.CountRelationships())
+	g.store.ForEachRelationship(func(item Relationship) bool {
+		if matchesRelationship(item, filters) {
+			matchingRelationships = append(matchingRelationships, item)
+		}
+		return true
+	})
+	sortRelationships(matchingRelationships)
+	return matchingRelationships
 }
 
-func (c *ChainLink) String() string {
-	var sb strings.Builder
-	if c.node.GetID() != "" {
-		sb.WriteString(c.node.String())
+// lessRelationship orders relationships by the names of the nodes they start from and point to, then label and ID
+func lessRelationship(a, b Relationship) bool {
+	if a.FromName != b.FromName {
+		return a.FromName < b.FromName
 	}
-	if c.rel.ID != "" {
-		sb.WriteString(fmt.Sprintf("->%s", c.rel.String()))
+	if a.ToName != b.ToName {
+		return a.ToName < b.ToName
 	}
-	if c.next != nil {
-		sb.WriteString(fmt.Sprintf("->%s", c.next.String()))
+	if a.Label != b.Label {
+		return a.Label < b.Label
 	}
-	return sb.String()
+	return a.ID < b.ID
+}
+
+func sortRelationships(rels []Relationship) {
+	sort.Slice(rels, func(i, j int) bool {
+		return lessRelationship(rels[i], rels[j])
+	})
+}
+
+// ListConnections returns all the chains of relationships that lead from one node to the other, without passing through the same node twice.
+// The search is not bounded; use ListConnectionsContext to limit it
+func (g *Graph) ListConnections(from, to Node) []*ChainLink {
+	// without limits and with a context that is never cancelled, the search can not fail
+	chains, _ := g.ListConnectionsContext(context.Background(), from, to, PathOptions{})
+	return chains
 }
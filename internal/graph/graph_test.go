@@ -33,7 +33,7 @@ var (
 
 func Test_Graph_Insert(t *testing.T) {
 	grf := graph.New()
-	createdNode := grf.InsertNode(bobita, puppyType, bobitaBody)
+	createdNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
 	node, err := grf.GetNodeByID(createdNode.GetID())
 	assert.NoError(t, err)
 	assert.Equal(t, bobitaBody, node.Body)
@@ -42,7 +42,7 @@ func Test_Graph_Insert(t *testing.T) {
 
 func Test_Graph_Insert_Imutable(t *testing.T) {
 	grf := graph.New()
-	createdNode := grf.InsertNode(bobita, puppyType, bobitaBody)
+	createdNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
 	node, err := grf.GetNodeByID(createdNode.GetID())
 	node.Body = []byte{}
 	assert.NoError(t, err)
@@ -73,8 +73,8 @@ func Test_Graph_AddRelationshipConcurrently(t *testing.T) {
 	concurrencyCount := 100
 	types := make([]string, concurrencyCount)
 	grf := graph.New()
-	createdNodeOne := grf.InsertNode(bobita, puppyType, bobitaBody)
-	createdNodeTwo := grf.InsertNode(azor, puppyType, azorBody)
+	createdNodeOne, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	createdNodeTwo, _ := grf.InsertNode(azor, puppyType, azorBody)
 	for i := 0; i < concurrencyCount; i++ {
 		types[i] = fmt.Sprintf("type-%d", i)
 	}
@@ -108,7 +108,7 @@ func Test_Graph_List(t *testing.T) {
 
 func Test_Graph_ListNodes_FilterByLabel(t *testing.T) {
 	grf := graph.New()
-	bNode := grf.InsertNode(bobita, puppyType, bobitaBody)
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
 	grf.InsertNode(smaug, dragonType, smaugBody)
 	foundNodes := grf.ListNodes(graph.FilterNodesByLabel(puppyType))
 	assert.Equal(t, 1, len(foundNodes))
@@ -117,7 +117,7 @@ func Test_Graph_ListNodes_FilterByLabel(t *testing.T) {
 
 func Test_Graph_ListNodes_FilterByName(t *testing.T) {
 	grf := graph.New()
-	bNode := grf.InsertNode(bobita, puppyType, bobitaBody)
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
 	grf.InsertNode(smaug, dragonType, smaugBody)
 	foundNodes := grf.ListNodes(graph.FilterNodesByName(bobita))
 	assert.Equal(t, 1, len(foundNodes))
@@ -126,14 +126,14 @@ func Test_Graph_ListNodes_FilterByName(t *testing.T) {
 
 func Test_Graph_ListNodes_Filter(t *testing.T) {
 	grf := graph.New()
-	whereCond := func(body graph.Node) bool {
+	whereCond := graph.FilterNodes(func(body graph.Node) bool {
 		pup := puppy{}
 		if err := json.Unmarshal(body.Body, &pup); err != nil {
 			return false
 		}
 		return pup.Power > 499
-	}
-	bNode := grf.InsertNode(bobita, puppyType, bobitaBody)
+	})
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
 	grf.InsertNode(azor, puppyType, azorBody)
 	foundNodes := grf.ListNodes(whereCond)
 	assert.Equal(t, 1, len(foundNodes))
@@ -142,8 +142,8 @@ func Test_Graph_ListNodes_Filter(t *testing.T) {
 
 func Test_Graph_AddRelationship(t *testing.T) {
 	grf := graph.New()
-	bNode := grf.InsertNode(bobita, puppyType, bobitaBody)
-	aNode := grf.InsertNode(azor, puppyType, azorBody)
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
 	rel1, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
 	assert.NoError(t, err)
 	rel2, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "competitors")
@@ -157,22 +157,22 @@ func Test_Graph_AddRelationship(t *testing.T) {
 
 func Test_Graph_AddRelationship_NoFrom(t *testing.T) {
 	grf := graph.New()
-	aNode := grf.InsertNode(azor, puppyType, azorBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
 	_, err := grf.AddRelationship("bNode.GetID()", aNode.GetID(), "friends")
 	assert.Error(t, err)
 }
 
 func Test_Graph_AddRelationship_NoTo(t *testing.T) {
 	grf := graph.New()
-	aNode := grf.InsertNode(azor, puppyType, azorBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
 	_, err := grf.AddRelationship(aNode.GetID(), "bNode.GetID()", "friends")
 	assert.Error(t, err)
 }
 
 func Test_Graph_GetRelationship(t *testing.T) {
 	grf := graph.New()
-	bNode := grf.InsertNode(bobita, puppyType, bobitaBody)
-	aNode := grf.InsertNode(azor, puppyType, azorBody)
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
 	initialRel, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
 	assert.NoError(t, err)
 	foundRel, err := grf.GetRelationshipByID(initialRel.ID)
@@ -189,9 +189,9 @@ func Test_Graph_GetRelationship_NotFound(t *testing.T) {
 
 func Test_Graph_ListRelationships(t *testing.T) {
 	grf := graph.New()
-	bNode := grf.InsertNode(bobita, puppyType, bobitaBody)
-	aNode := grf.InsertNode(azor, puppyType, azorBody)
-	dNode := grf.InsertNode(smaug, dragonType, smaugBody)
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
 	rel1, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
 	assert.NoError(t, err)
 	rel2, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "competitors")
@@ -207,9 +207,9 @@ func Test_Graph_ListRelationships(t *testing.T) {
 
 func Test_Graph_ListRelationships_Filter(t *testing.T) {
 	grf := graph.New()
-	bNode := grf.InsertNode(bobita, puppyType, bobitaBody)
-	aNode := grf.InsertNode(azor, puppyType, azorBody)
-	dNode := grf.InsertNode(smaug, dragonType, smaugBody)
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
 	rel1, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
 	assert.NoError(t, err)
 	_, err = grf.AddRelationship(bNode.GetID(), aNode.GetID(), "competitors")
@@ -223,9 +223,9 @@ func Test_Graph_ListRelationships_Filter(t *testing.T) {
 
 func Test_Graph_ListRelationships_FilterByFrom(t *testing.T) {
 	grf := graph.New()
-	bNode := grf.InsertNode(bobita, puppyType, bobitaBody)
-	aNode := grf.InsertNode(azor, puppyType, azorBody)
-	dNode := grf.InsertNode(smaug, dragonType, smaugBody)
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
 	rel1, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
 	assert.NoError(t, err)
 	_, err = grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
@@ -237,9 +237,9 @@ func Test_Graph_ListRelationships_FilterByFrom(t *testing.T) {
 
 func Test_Graph_ListRelationships_FilterByTo(t *testing.T) {
 	grf := graph.New()
-	bNode := grf.InsertNode(bobita, puppyType, bobitaBody)
-	aNode := grf.InsertNode(azor, puppyType, azorBody)
-	dNode := grf.InsertNode(smaug, dragonType, smaugBody)
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
 	rel1, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
 	assert.NoError(t, err)
 	_, err = grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
@@ -251,9 +251,9 @@ func Test_Graph_ListRelationships_FilterByTo(t *testing.T) {
 
 func Test_Graph_ListConnections(t *testing.T) {
 	grf := graph.New()
-	bNode := grf.InsertNode(bobita, puppyType, bobitaBody)
-	aNode := grf.InsertNode(azor, puppyType, azorBody)
-	dNode := grf.InsertNode(smaug, dragonType, smaugBody)
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
 	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
 	assert.NoError(t, err)
 	_, err = grf.AddRelationship(bNode.GetID(), dNode.GetID(), "enemies")
@@ -271,3 +271,517 @@ func Test_Graph_ListConnections(t *testing.T) {
 	cons := grf.ListConnections(bNode, dNode)
 	assert.Len(t, cons, 2)
 }
+
+func Test_Graph_ListNodes_FilterByNameAndLabel(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	grf.InsertNode(bobita, dragonType, bobitaBody)
+	grf.InsertNode(azor, puppyType, azorBody)
+	foundNodes := grf.ListNodes(graph.FilterNodesByLabel(puppyType), graph.FilterNodesByName(bobita))
+	assert.Equal(t, 1, len(foundNodes))
+	assert.Equal(t, bNode.GetID(), foundNodes[0].GetID())
+	assert.Len(t, grf.ListNodes(graph.FilterNodesByName(bobita, azor)), 3)
+	assert.Len(t, grf.ListNodes(graph.FilterNodesByName("missing")), 0)
+	assert.Len(t, grf.ListNodes(graph.FilterNodesByLabel()), 0)
+}
+
+func Test_Graph_ListNodes_IndexedAndCustomFilter(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	grf.InsertNode(azor, puppyType, azorBody)
+	strong := graph.FilterNodes(func(node graph.Node) bool {
+		pup := puppy{}
+		if err := json.Unmarshal(node.Body, &pup); err != nil {
+			return false
+		}
+		return pup.Power > 499
+	})
+	foundNodes := grf.ListNodes(strong, graph.FilterNodesByLabel(puppyType))
+	assert.Equal(t, 1, len(foundNodes))
+	assert.Equal(t, bNode.GetID(), foundNodes[0].GetID())
+}
+
+func Test_Graph_ListNodes_IndexFilter(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	grf.InsertNode(azor, puppyType, azorBody)
+	grf.InsertNode(smaug, dragonType, smaugBody)
+
+	byName := graph.IndexFilter{Index: graph.NameIndex, Keys: []string{bobita, smaug}}
+	assert.Len(t, grf.ListNodes(byName), 2)
+	assert.Equal(t, []graph.Node{bNode}, grf.ListNodes(byName, graph.FilterNodesByLabel(puppyType)))
+	assert.True(t, byName.Match(bNode))
+	assert.True(t, graph.FilterNodesByField("power", 500).Match(bNode))
+	assert.False(t, graph.FilterNodesByField("power", 457).Match(bNode))
+}
+
+func newBenchmarkGraph(size int) *graph.Graph {
+	grf := graph.New()
+	for i := 0; i < size; i++ {
+		grf.InsertNode(fmt.Sprintf("item-%d", i), fmt.Sprintf("type-%d", i%10), []byte{})
+	}
+	return grf
+}
+
+// Benchmark_Graph_InsertNode_AfterView takes a view before each insert, so that every insert changes a graph it shares with a view
+func Benchmark_Graph_InsertNode_AfterSnapshot(b *testing.B) {
+	grf := newBenchmarkGraph(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		grf.Snapshot()
+		grf.InsertNode(fmt.Sprintf("dragon-%d", i), dragonType, smaugBody)
+	}
+}
+
+func Benchmark_Graph_ListNodes_ByName_Indexed(b *testing.B) {
+	grf := newBenchmarkGraph(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		grf.ListNodes(graph.FilterNodesByName(fmt.Sprintf("item-%d", i%10000)))
+	}
+}
+
+func Benchmark_Graph_ListNodes_ByName_Scan(b *testing.B) {
+	grf := newBenchmarkGraph(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		name := fmt.Sprintf("item-%d", i%10000)
+		// a plain filter function can not use the index, so all the nodes are checked
+		grf.ListNodes(graph.FilterNodes(func(node graph.Node) bool {
+			return node.GetName() == name
+		}))
+	}
+}
+
+func Benchmark_Graph_ListNodes_ByLabel_Indexed(b *testing.B) {
+	grf := newBenchmarkGraph(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		grf.ListNodes(graph.FilterNodesByLabel(fmt.Sprintf("type-%d", i%10)))
+	}
+}
+
+func Benchmark_Graph_ListNodes_ByLabel_Scan(b *testing.B) {
+	grf := newBenchmarkGraph(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		label := fmt.Sprintf("type-%d", i%10)
+		grf.ListNodes(graph.FilterNodes(func(node graph.Node) bool {
+			return node.GetLabel() == label
+		}))
+	}
+}
+
+func Test_Graph_OutgoingIncoming(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	rel1, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	rel2, err := grf.AddRelationship(bNode.GetID(), dNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	rel3, err := grf.AddRelationship(dNode.GetID(), aNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	assert.ElementsMatch(t, []graph.Relationship{rel1, rel2}, grf.Outgoing(bNode.GetID()))
+	assert.ElementsMatch(t, []graph.Relationship{rel2}, grf.Outgoing(bNode.GetID(), "enemies"))
+	assert.ElementsMatch(t, []graph.Relationship{rel1, rel3}, grf.Incoming(aNode.GetID()))
+	assert.ElementsMatch(t, []graph.Relationship{rel1}, grf.Incoming(aNode.GetID(), "friends", "competitors"))
+	assert.Empty(t, grf.Outgoing(aNode.GetID()))
+	assert.Empty(t, grf.Incoming("missing"))
+}
+
+func Test_Graph_DeleteNode(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	grf.InsertNode(azor, puppyType, azorBody)
+	assert.NoError(t, grf.DeleteNode(bNode.GetID(), graph.RejectDangling))
+	_, err := grf.GetNodeByID(bNode.GetID())
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+	assert.Len(t, grf.ListNodes(), 1)
+	assert.Empty(t, grf.ListNodes(graph.FilterNodesByName(bobita)))
+}
+
+func Test_Graph_DeleteNode_NotFound(t *testing.T) {
+	grf := graph.New()
+	err := grf.DeleteNode("fake", graph.CascadeRelationships)
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+}
+
+func Test_Graph_DeleteNode_RejectDangling(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	err = grf.DeleteNode(aNode.GetID(), graph.RejectDangling)
+	assert.ErrorIs(t, err, graph.ErrHasRelationships)
+	assert.Len(t, grf.ListNodes(), 2)
+	assert.Len(t, grf.ListRelationships(), 1)
+}
+
+func Test_Graph_DeleteNode_Cascade(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(aNode.GetID(), dNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	rel, err := grf.AddRelationship(bNode.GetID(), dNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	assert.NoError(t, grf.DeleteNode(aNode.GetID(), graph.CascadeRelationships))
+	assert.Equal(t, []graph.Relationship{rel}, grf.ListRelationships())
+	assert.Equal(t, []graph.Relationship{rel}, grf.Outgoing(bNode.GetID()))
+	assert.Equal(t, []graph.Relationship{rel}, grf.Incoming(dNode.GetID()))
+}
+
+func Test_Graph_DeleteRelationship(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	rel, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	assert.NoError(t, grf.DeleteRelationship(rel.ID))
+	assert.Empty(t, grf.ListRelationships())
+	assert.Empty(t, grf.Outgoing(bNode.GetID()))
+	assert.Empty(t, grf.Incoming(aNode.GetID()))
+	assert.Len(t, grf.ListNodes(), 2)
+	assert.ErrorIs(t, grf.DeleteRelationship(rel.ID), graph.ErrNotFound)
+}
+
+func Test_Graph_DeleteNodeConcurrently(t *testing.T) {
+	concurrencyCount := 100
+	grf := graph.New()
+	ids := make([]string, concurrencyCount)
+	for i := 0; i < concurrencyCount; i++ {
+		node, _ := grf.InsertNode(fmt.Sprintf("item-%d", i), puppyType, []byte{})
+		ids[i] = node.GetID()
+	}
+	var wg sync.WaitGroup
+	wg.Add(concurrencyCount)
+	for i := 0; i < concurrencyCount; i++ {
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, grf.DeleteNode(ids[i], graph.RejectDangling))
+		}(i)
+	}
+	wg.Wait()
+	assert.Empty(t, grf.ListNodes())
+}
+
+func Test_Graph_DeleteRelationshipConcurrently(t *testing.T) {
+	concurrencyCount := 100
+	grf := graph.New()
+	createdNodeOne, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	createdNodeTwo, _ := grf.InsertNode(azor, puppyType, azorBody)
+	ids := make([]string, concurrencyCount)
+	for i := 0; i < concurrencyCount; i++ {
+		rel, err := grf.AddRelationship(createdNodeOne.GetID(), createdNodeTwo.GetID(), fmt.Sprintf("item-%d", i))
+		assert.NoError(t, err)
+		ids[i] = rel.ID
+	}
+	var wg sync.WaitGroup
+	wg.Add(concurrencyCount)
+	for i := 0; i < concurrencyCount; i++ {
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, grf.DeleteRelationship(ids[i]))
+		}(i)
+	}
+	wg.Wait()
+	assert.Empty(t, grf.ListRelationships())
+	assert.Empty(t, grf.Outgoing(createdNodeOne.GetID()))
+}
+
+func Test_Graph_DeleteNodeWhileAddingRelationships(t *testing.T) {
+	concurrencyCount := 100
+	grf := graph.New()
+	createdNodeOne, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	createdNodeTwo, _ := grf.InsertNode(azor, puppyType, azorBody)
+	var wg sync.WaitGroup
+	wg.Add(concurrencyCount + 1)
+	for i := 0; i < concurrencyCount; i++ {
+		go func(i int) {
+			defer wg.Done()
+			// the relationship is either added before the node is deleted, and removed along with it, or it is rejected
+			_, err := grf.AddRelationship(createdNodeOne.GetID(), createdNodeTwo.GetID(), fmt.Sprintf("item-%d", i))
+			if err != nil {
+				assert.ErrorIs(t, err, graph.ErrNotFound)
+			}
+		}(i)
+	}
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, grf.DeleteNode(createdNodeTwo.GetID(), graph.CascadeRelationships))
+	}()
+	wg.Wait()
+	assert.Empty(t, grf.ListRelationships())
+	assert.Empty(t, grf.Outgoing(createdNodeOne.GetID()))
+}
+
+func Test_Graph_UpsertNode_Insert(t *testing.T) {
+	grf := graph.New()
+	node, err := grf.UpsertNode(bobita, puppyType, bobitaBody)
+	assert.NoError(t, err)
+	found, err := grf.GetNodeByID(node.GetID())
+	assert.NoError(t, err)
+	assert.Equal(t, bobitaBody, found.Body)
+	assert.Len(t, grf.ListNodes(), 1)
+}
+
+func Test_Graph_UpsertNode_SameKey(t *testing.T) {
+	grf := graph.New()
+	placeholder, err := grf.UpsertNode(bobita, puppyType, []byte{})
+	assert.NoError(t, err)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	rel, err := grf.AddRelationship(aNode.GetID(), placeholder.GetID(), "friends")
+	assert.NoError(t, err)
+
+	node, err := grf.UpsertNode(bobita, puppyType, bobitaBody)
+	assert.NoError(t, err)
+	assert.Equal(t, placeholder.GetID(), node.GetID())
+	assert.Equal(t, bobitaBody, node.Body)
+	assert.Len(t, grf.ListNodes(), 2)
+	assert.Equal(t, []graph.Relationship{rel}, grf.Incoming(node.GetID()))
+
+	// an empty body does not overwrite the existing one
+	node, err = grf.UpsertNode(bobita, puppyType, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, bobitaBody, node.Body)
+}
+
+func Test_Graph_UpsertNode_DifferentLabel(t *testing.T) {
+	grf := graph.New()
+	pNode, err := grf.UpsertNode(bobita, puppyType, bobitaBody)
+	assert.NoError(t, err)
+	dNode, err := grf.UpsertNode(bobita, dragonType, smaugBody)
+	assert.NoError(t, err)
+	assert.NotEqual(t, pNode.GetID(), dNode.GetID())
+	assert.Len(t, grf.ListNodes(), 2)
+}
+
+func Test_Graph_UpsertNode_ReplaceBody(t *testing.T) {
+	grf := graph.New()
+	_, err := grf.UpsertNode(smaug, dragonType, smaugBody)
+	assert.NoError(t, err)
+	// fields missing from the new body are not kept from the old one
+	node, err := grf.UpsertNode(smaug, dragonType, []byte(`{"power":9000}`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"power":9000}`, string(node.Body))
+	found, err := grf.GetNodeByID(node.GetID())
+	assert.NoError(t, err)
+	assert.Equal(t, node, found)
+}
+
+func Test_Graph_UpsertNode_Ambiguous(t *testing.T) {
+	grf := graph.New()
+	grf.InsertNode(bobita, puppyType, bobitaBody)
+	grf.InsertNode(bobita, puppyType, bobitaBody)
+	_, err := grf.UpsertNode(bobita, puppyType, bobitaBody)
+	assert.ErrorIs(t, err, graph.ErrAmbiguousNode)
+}
+
+func Test_Graph_UpsertNodeConcurrently(t *testing.T) {
+	concurrencyCount := 100
+	grf := graph.New()
+	var wg sync.WaitGroup
+	wg.Add(concurrencyCount)
+	for i := 0; i < concurrencyCount; i++ {
+		go func(i int) {
+			defer wg.Done()
+			_, err := grf.UpsertNode(fmt.Sprintf("item-%d", i%10), puppyType, []byte{})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, grf.ListNodes(), 10)
+}
+
+func Test_Graph_UpsertNodeInNamespace(t *testing.T) {
+	grf := graph.New()
+	a, err := grf.UpsertNodeInNamespace("a", "n1", "label", []byte("a"))
+	assert.NoError(t, err)
+	b, err := grf.UpsertNodeInNamespace("b", "n1", "label", []byte("b"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, a.GetID(), b.GetID())
+
+	again, err := grf.UpsertNodeInNamespace("a", "n1", "label", []byte("a2"))
+	assert.NoError(t, err)
+	assert.Equal(t, a.GetID(), again.GetID())
+	assert.Equal(t, "a", again.GetNamespace())
+	assert.Equal(t, "a:n1", again.Reference())
+
+	assert.Len(t, grf.ListNodes(graph.FilterNodesByName("n1")), 2)
+	inA := grf.ListNodes(graph.FilterNodesByNamespace("a"))
+	assert.Len(t, inA, 1)
+	assert.Equal(t, []byte("a2"), inA[0].Body)
+	assert.Len(t, grf.ListNodes(graph.FilterNodesByNamespace("a", "b")), 2)
+	assert.Empty(t, grf.ListNodes(graph.FilterNodesByNamespace("a"), graph.FilterNodesByLabel("other")))
+
+	// nodes inserted without a namespace are in the default one
+	def, err := grf.UpsertNode("n1", "label", []byte{})
+	assert.NoError(t, err)
+	assert.Equal(t, "", def.GetNamespace())
+	assert.Equal(t, "n1", def.Reference())
+	assert.Len(t, grf.ListNodes(graph.FilterNodesByNamespace("")), 1)
+}
+
+func Test_ParseReference(t *testing.T) {
+	namespace, name, qualified := graph.ParseReference("account:vpc-1")
+	assert.True(t, qualified)
+	assert.Equal(t, "account", namespace)
+	assert.Equal(t, "vpc-1", name)
+
+	namespace, name, qualified = graph.ParseReference("vpc-1")
+	assert.False(t, qualified)
+	assert.Equal(t, "", namespace)
+	assert.Equal(t, "vpc-1", name)
+
+	// only the first separator splits the reference
+	namespace, name, _ = graph.ParseReference("a:b:c")
+	assert.Equal(t, "a", namespace)
+	assert.Equal(t, "b:c", name)
+}
+
+func Test_Graph_Snapshot_ReadOnly(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	rel, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	view := grf.Snapshot()
+	_, err = grf.UpsertNode(azor, puppyType, []byte(`{"power":1}`))
+	assert.NoError(t, err)
+	assert.NoError(t, grf.DeleteNode(bNode.GetID(), graph.CascadeRelationships))
+	grf.InsertNode(smaug, dragonType, smaugBody)
+
+	assert.ElementsMatch(t, []graph.Node{bNode, aNode}, view.ListNodes())
+	assert.Equal(t, []graph.Relationship{rel}, view.ListRelationships())
+	assert.Equal(t, []graph.Relationship{rel}, view.Incoming(aNode.GetID()))
+	assert.Equal(t, []graph.Node{aNode}, view.ListNodes(graph.FilterNodesByName(azor)))
+	assert.Len(t, grf.ListNodes(), 2)
+	assert.Empty(t, grf.ListRelationships())
+
+	_, err = view.UpsertNode(smaug, dragonType, smaugBody)
+	assert.ErrorIs(t, err, graph.ErrReadOnly)
+	_, err = view.AddRelationship(aNode.GetID(), bNode.GetID(), "friends")
+	assert.ErrorIs(t, err, graph.ErrReadOnly)
+	assert.ErrorIs(t, view.DeleteNode(aNode.GetID(), graph.CascadeRelationships), graph.ErrReadOnly)
+	assert.ErrorIs(t, view.DeleteRelationship(rel.ID), graph.ErrReadOnly)
+	assert.ErrorIs(t, view.Batch(func(tx *graph.Tx) error { return nil }), graph.ErrReadOnly)
+	_, err = view.InsertNode(smaug, dragonType, smaugBody)
+	assert.ErrorIs(t, err, graph.ErrReadOnly)
+	assert.NoError(t, view.Err())
+	view.IndexField(puppyType, "power")
+	assert.ErrorIs(t, view.Err(), graph.ErrReadOnly)
+	assert.Empty(t, view.IndexedFields(puppyType))
+	assert.Equal(t, []graph.Node{aNode}, view.ListNodes(graph.FilterNodesByField("power", 457)))
+	assert.Len(t, view.ListNodes(), 2)
+	assert.NoError(t, grf.Err())
+}
+
+func Test_Graph_Snapshot_Isolation(t *testing.T) {
+	grf := graph.New()
+	views := []*graph.Graph{}
+	expected := [][]string{}
+	names := map[string]string{}
+	for i := 0; i < 2000; i++ {
+		node, _ := grf.InsertNode(fmt.Sprintf("puppy-%d", i), puppyType, bobitaBody)
+		names[node.GetID()] = node.GetName()
+		// every third node is removed again, so that the views also share the parts of the graph that deletes change
+		if i%3 == 2 {
+			for id := range names {
+				assert.NoError(t, grf.DeleteNode(id, graph.RejectDangling))
+				delete(names, id)
+				break
+			}
+		}
+		if i%250 == 0 {
+			views = append(views, grf.Snapshot())
+			version := []string{}
+			for _, name := range names {
+				version = append(version, name)
+			}
+			expected = append(expected, version)
+		}
+	}
+	for i, view := range views {
+		found := []string{}
+		for _, node := range view.ListNodes(graph.FilterNodesByLabel(puppyType)) {
+			found = append(found, node.GetName())
+		}
+		assert.ElementsMatch(t, expected[i], found)
+		assert.Len(t, view.ListNodes(graph.FilterNodesByName(expected[i]...)), len(expected[i]))
+	}
+	assert.Len(t, grf.ListNodes(), len(names))
+}
+
+func Test_Graph_Snapshot_ConcurrentInserts(t *testing.T) {
+	stores := map[string]func() (graph.Store, error){
+		"memory": func() (graph.Store, error) { return graph.NewMemoryStore(), nil },
+		"disk":   func() (graph.Store, error) { return graph.NewTempDiskStore(t.TempDir()) },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store, err := newStore()
+			assert.NoError(t, err)
+			grf, err := graph.NewWithStore(store)
+			assert.NoError(t, err)
+			defer grf.Close()
+
+			inserts := 200
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < inserts; i++ {
+					err := grf.Batch(func(tx *graph.Tx) error {
+						puppy, _ := tx.InsertNode(fmt.Sprintf("puppy-%d", i), puppyType, bobitaBody)
+						dragon, _ := tx.InsertNode(fmt.Sprintf("dragon-%d", i), dragonType, smaugBody)
+						_, err := tx.AddRelationship(puppy.GetID(), dragon.GetID(), "fears")
+						return err
+					})
+					assert.NoError(t, err)
+				}
+			}()
+
+			// every puppy is inserted along with a dragon it fears, so a consistent view has as many puppies as dragons and relationships
+			var wg sync.WaitGroup
+			wg.Add(4)
+			for r := 0; r < 4; r++ {
+				go func() {
+					defer wg.Done()
+					for {
+						select {
+						case <-done:
+							return
+						default:
+						}
+						view := grf.Snapshot()
+						puppies := view.ListNodes(graph.FilterNodesByLabel(puppyType))
+						dragons := view.ListNodes(graph.FilterNodesByLabel(dragonType))
+						rels := view.ListRelationships()
+						assert.Equal(t, len(puppies), len(dragons))
+						assert.Equal(t, len(puppies), len(rels))
+						for _, puppy := range puppies {
+							fears := view.Outgoing(puppy.GetID(), "fears")
+							if assert.Len(t, fears, 1) {
+								_, err := view.GetNodeByID(fears[0].To)
+								assert.NoError(t, err)
+							}
+						}
+						// the view does not change while the graph does
+						assert.Len(t, view.ListNodes(), len(puppies)+len(dragons))
+						assert.NoError(t, view.Err())
+					}
+				}()
+			}
+			wg.Wait()
+			assert.Len(t, grf.ListNodes(), 2*inserts)
+			assert.Len(t, grf.ListRelationships(), inserts)
+		})
+	}
+}
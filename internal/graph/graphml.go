@@ -0,0 +1,153 @@
+package graph
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+const (
+	graphMLNameKey         = "name"
+	graphMLLabelKey        = "label"
+	graphMLNamespaceKey    = "namespace"
+	graphMLRelationshipKey = "relationship"
+	graphMLFieldKeyPrefix  = "body."
+)
+
+// WriteGraphML writes the graph in the GraphML format. Besides its name, label and namespace, each field of a node json body is written as a data attribute.
+// Scalar fields are written as they are, while lists and objects are written as json
+func (g *Graph) WriteGraphML(w io.Writer, opts ExportOptions) error {
+	view, err := g.exportView(opts)
+	if err != nil {
+		return err
+	}
+	doc := graphMLDocument{
+		XMLNS: graphMLNamespace,
+		Keys: []graphMLKey{
+			{ID: graphMLNameKey, For: "node", AttrName: "name", AttrType: "string"},
+			{ID: graphMLLabelKey, For: "node", AttrName: "label", AttrType: "string"},
+			{ID: graphMLNamespaceKey, For: "node", AttrName: "namespace", AttrType: "string"},
+			{ID: graphMLRelationshipKey, For: "edge", AttrName: "label", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "assets", EdgeDefault: "directed"},
+	}
+	fields := map[string]struct{}{}
+	for _, node := range view.nodes {
+		data := []graphMLData{
+			{Key: graphMLNameKey, Value: node.name},
+			{Key: graphMLLabelKey, Value: node.label},
+		}
+		if node.namespace != "" {
+			data = append(data, graphMLData{Key: graphMLNamespaceKey, Value: node.namespace})
+		}
+		bodyFields, err := bodyAttributes(node.Body)
+		if err != nil {
+			return fmt.Errorf("could not read body of node %s; %w", node.name, err)
+		}
+		names := make([]string, 0, len(bodyFields))
+		for name := range bodyFields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fields[name] = struct{}{}
+			data = append(data, graphMLData{Key: graphMLFieldKeyPrefix + name, Value: bodyFields[name]})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: node.id, Data: data})
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: graphMLFieldKeyPrefix + name, For: "node", AttrName: name, AttrType: "string"})
+	}
+	for _, rel := range view.relationships {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     rel.ID,
+			Source: rel.From,
+			Target: rel.To,
+			Data:   []graphMLData{{Key: graphMLRelationshipKey, Value: rel.Label}},
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("could not encode graphml; %w", err)
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// bodyAttributes returns the top level fields of a json object body as strings. Empty bodies, like those of placeholder nodes, have no fields
+func bodyAttributes(body []byte) (map[string]string, error) {
+	attributes := map[string]string{}
+	if len(body) == 0 {
+		return attributes, nil
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	for name, raw := range fields {
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		switch v := value.(type) {
+		case string:
+			attributes[name] = v
+		case nil:
+			attributes[name] = ""
+		default:
+			attributes[name] = string(raw)
+		}
+	}
+	return attributes, nil
+}
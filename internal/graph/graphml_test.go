@@ -0,0 +1,65 @@
+package graph_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+type graphML struct {
+	Keys []struct {
+		ID       string `xml:"id,attr"`
+		AttrName string `xml:"attr.name,attr"`
+	} `xml:"key"`
+	Nodes []struct {
+		ID   string `xml:"id,attr"`
+		Data []struct {
+			Key   string `xml:"key,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"data"`
+	} `xml:"graph>node"`
+	Edges []struct {
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+	} `xml:"graph>edge"`
+}
+
+func Test_Graph_WriteGraphML(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, []byte(`{"canFly":true,"treasure":["gold","gems"]}`))
+	grf.InsertNode(azor, puppyType, []byte{})
+	_, err := grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grf.WriteGraphML(&buf, graph.ExportOptions{}))
+	doc := graphML{}
+	assert.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+
+	keys := map[string]string{}
+	for _, key := range doc.Keys {
+		keys[key.ID] = key.AttrName
+	}
+	assert.Equal(t, "power", keys["body.power"])
+	assert.Equal(t, "treasure", keys["body.treasure"])
+
+	assert.Len(t, doc.Nodes, 3)
+	data := map[string]map[string]string{}
+	for _, node := range doc.Nodes {
+		data[node.ID] = map[string]string{}
+		for _, d := range node.Data {
+			data[node.ID][d.Key] = d.Value
+		}
+	}
+	assert.Equal(t, map[string]string{"name": bobita, "label": puppyType, "body.name": "Bobita", "body.power": "500"}, data[bNode.GetID()])
+	assert.Equal(t, map[string]string{"name": smaug, "label": dragonType, "body.canFly": "true", "body.treasure": `["gold","gems"]`}, data[dNode.GetID()])
+
+	assert.Len(t, doc.Edges, 1)
+	assert.Equal(t, dNode.GetID(), doc.Edges[0].Source)
+	assert.Equal(t, bNode.GetID(), doc.Edges[0].Target)
+}
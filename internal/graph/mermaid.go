@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// mermaidShapes maps the DOT shapes used in NodeStyle to the closest Mermaid flowchart shape, as opening and closing delimiters
+var mermaidShapes = map[string][2]string{
+	"box":       {"[", "]"},
+	"ellipse":   {"([", "])"},
+	"octagon":   {"{{", "}}"},
+	"hexagon":   {"{{", "}}"},
+	"folder":    {"[[", "]]"},
+	"component": {"[[", "]]"},
+	"diamond":   {"{", "}"},
+	"cylinder":  {"[(", ")]"},
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart. The node label decides the shape and colour of each node, and relationships are labeled with their label
+func (g *Graph) WriteMermaid(w io.Writer, opts ExportOptions) error {
+	view, err := g.exportView(opts)
+	if err != nil {
+		return err
+	}
+	return writeMermaid(w, view)
+}
+
+// WriteMermaidPaths writes the given chains as a single Mermaid flowchart, drawing the nodes with the given styles. Nodes and relationships that are part of several chains are only drawn once
+func WriteMermaidPaths(w io.Writer, chains []*ChainLink, styles map[string]NodeStyle) error {
+	view := exportView{
+		styles:           map[string]NodeStyle{},
+		highlightedNodes: map[string]struct{}{},
+		highlightedRels:  map[string]struct{}{},
+	}
+	seenNodes := map[string]struct{}{}
+	seenRels := map[string]struct{}{}
+	labels := []string{}
+	for _, chain := range chains {
+		for _, node := range chain.Nodes() {
+			if _, ok := seenNodes[node.id]; ok {
+				continue
+			}
+			seenNodes[node.id] = struct{}{}
+			view.nodes = append(view.nodes, node)
+			if _, ok := view.styles[node.label]; !ok {
+				view.styles[node.label] = NodeStyle{}
+				labels = append(labels, node.label)
+			}
+		}
+		for _, rel := range chain.Relationships() {
+			if _, ok := seenRels[rel.ID]; ok {
+				continue
+			}
+			seenRels[rel.ID] = struct{}{}
+			view.relationships = append(view.relationships, rel)
+		}
+	}
+	sort.Strings(labels)
+	for i, label := range labels {
+		style, ok := styles[label]
+		if !ok {
+			style = NodeStyle{Shape: defaultShapes[i%len(defaultShapes)], Color: defaultColors[i%len(defaultColors)]}
+		}
+		view.styles[label] = style
+	}
+	return writeMermaid(w, view)
+}
+
+func writeMermaid(w io.Writer, view exportView) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "flowchart LR")
+	// the node IDs contain characters Mermaid does not accept, so nodes get short identifiers based on their position
+	ids := map[string]string{}
+	for i, node := range view.nodes {
+		ids[node.id] = fmt.Sprintf("n%d", i)
+		delimiters, ok := mermaidShapes[view.styles[node.label].Shape]
+		if !ok {
+			delimiters = mermaidShapes["box"]
+		}
+		fmt.Fprintf(out, "    %s%s%s%s\n", ids[node.id], delimiters[0], mermaidQuote(node.Reference()), delimiters[1])
+	}
+	highlightedLinks := []string{}
+	link := 0
+	for _, rel := range view.relationships {
+		from, fromOK := ids[rel.From]
+		to, toOK := ids[rel.To]
+		if !fromOK || !toOK {
+			continue
+		}
+		fmt.Fprintf(out, "    %s -->|%s| %s\n", from, mermaidQuote(rel.Label), to)
+		if _, ok := view.highlightedRels[rel.ID]; ok {
+			highlightedLinks = append(highlightedLinks, fmt.Sprint(link))
+		}
+		link++
+	}
+	labels := make([]string, 0, len(view.styles))
+	for label := range view.styles {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for i, label := range labels {
+		fmt.Fprintf(out, "    classDef c%d fill:%s\n", i, view.styles[label].Color)
+		members := []string{}
+		for _, node := range view.nodes {
+			if node.label == label {
+				members = append(members, ids[node.id])
+			}
+		}
+		fmt.Fprintf(out, "    class %s c%d\n", strings.Join(members, ","), i)
+	}
+	highlighted := []string{}
+	for _, node := range view.nodes {
+		if _, ok := view.highlightedNodes[node.id]; ok {
+			highlighted = append(highlighted, ids[node.id])
+		}
+	}
+	if len(highlighted) > 0 {
+		fmt.Fprintf(out, "    classDef highlighted stroke:%s,stroke-width:3px\n", highlightColor)
+		fmt.Fprintf(out, "    class %s highlighted\n", strings.Join(highlighted, ","))
+	}
+	if len(highlightedLinks) > 0 {
+		fmt.Fprintf(out, "    linkStyle %s stroke:%s,stroke-width:3px\n", strings.Join(highlightedLinks, ","), highlightColor)
+	}
+	return out.Flush()
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,69 @@
+package graph_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_Graph_WriteMermaid(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = grf.WriteMermaid(&buf, graph.ExportOptions{
+		Styles:    map[string]graph.NodeStyle{puppyType: {Shape: "ellipse", Color: "yellow"}},
+		Highlight: []string{aNode.GetID()},
+	})
+	assert.NoError(t, err)
+	expected := strings.Join([]string{
+		"flowchart LR",
+		`    n0["Smaug"]`,
+		`    n1(["Azor"])`,
+		`    n2(["Bobita"])`,
+		`    n2 -->|"friends"| n1`,
+		`    n0 -->|"enemies"| n2`,
+		"    classDef c0 fill:lightblue",
+		"    class n0 c0",
+		"    classDef c1 fill:yellow",
+		"    class n1,n2 c1",
+		"    classDef highlighted stroke:red,stroke-width:3px",
+		"    class n1 highlighted",
+		"",
+	}, "\n")
+	assert.Equal(t, expected, buf.String())
+}
+
+func Test_WriteMermaidPaths(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(aNode.GetID(), dNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(bNode.GetID(), dNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	chains := grf.ListConnections(bNode, dNode)
+	assert.Len(t, chains, 2)
+
+	var buf bytes.Buffer
+	assert.NoError(t, graph.WriteMermaidPaths(&buf, chains, nil))
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "flowchart LR\n"))
+	assert.Equal(t, 1, strings.Count(out, `"Bobita"`))
+	assert.Equal(t, 1, strings.Count(out, `"Azor"`))
+	assert.Equal(t, 1, strings.Count(out, `"Smaug"`))
+	assert.Equal(t, 3, strings.Count(out, "-->"))
+}
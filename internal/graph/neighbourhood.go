@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"fmt"
+)
+
+// Neighbourhood returns a new graph holding the nodes that can be reached from the given node by following at most the given number of relationships in the direction given,
+// along with all the relationships between those nodes. Nodes and relationships keep their IDs, and the new graph does not share any data with this one.
+// If the store fails to read the graph, its error is returned instead of a partial neighbourhood
+func (g *Graph) Neighbourhood(nodeID string, hops int, direction Direction) (*Graph, error) {
+	if hops < 0 {
+		return nil, fmt.Errorf("the number of hops must not be negative; got %d", hops)
+	}
+	g.RLock()
+	defer g.RUnlock()
+	if !g.store.HasNode(nodeID) {
+		return nil, fmt.Errorf("%w; node with id '%s'", ErrNotFound, nodeID)
+	}
+	reached := map[string]struct{}{nodeID: {}}
+	level := []string{nodeID}
+	for depth := 0; depth < hops && len(level) > 0; depth++ {
+		next := []string{}
+		for _, current := range level {
+			for _, h := range g.hops(current, direction, nil) {
+				if _, ok := reached[h.to]; ok {
+					continue
+				}
+				if !g.store.HasNode(h.to) {
+					continue
+				}
+				reached[h.to] = struct{}{}
+				next = append(next, h.to)
+			}
+		}
+		level = next
+	}
+
+	sub := New()
+	for id := range reached {
+		node := g.node(id)
+		body := make([]byte, len(node.Body))
+		copy(body, node.Body)
+		sub.putNode(Node{
+			id:        node.id,
+			namespace: node.namespace,
+			name:      node.name,
+			label:     node.label,
+			Body:      body,
+		})
+	}
+	// the subgraph is induced: every relationship between two reached nodes is kept, even the ones that were not followed
+	for id := range reached {
+		for _, rel := range g.adjacent(outgoingIndexName, id, nil) {
+			if _, ok := reached[rel.To]; ok {
+				sub.putRelationship(rel)
+			}
+		}
+	}
+	if err := g.store.Err(); err != nil {
+		return nil, fmt.Errorf("could not read the graph; %w", err)
+	}
+	return sub, nil
+}
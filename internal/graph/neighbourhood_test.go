@@ -0,0 +1,76 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_Graph_Neighbourhood(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	farNode, _ := grf.InsertNode("Rex", puppyType, []byte{})
+	rel1, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	rel2, err := grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	rel3, err := grf.AddRelationship(aNode.GetID(), dNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(dNode.GetID(), farNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	sub, err := grf.Neighbourhood(bNode.GetID(), 1, graph.Outbound)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []graph.Node{bNode, aNode}, sub.ListNodes())
+	assert.ElementsMatch(t, []graph.Relationship{rel1}, sub.ListRelationships())
+
+	// smaug is reached backwards, and the relationship from azor to it is kept even if it was not followed
+	sub, err = grf.Neighbourhood(bNode.GetID(), 1, graph.Both)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []graph.Node{bNode, aNode, dNode}, sub.ListNodes())
+	assert.ElementsMatch(t, []graph.Relationship{rel1, rel2, rel3}, sub.ListRelationships())
+	assert.Len(t, sub.ListNodes(graph.FilterNodesByLabel(dragonType)), 1)
+
+	sub, err = grf.Neighbourhood(bNode.GetID(), 0, graph.Both)
+	assert.NoError(t, err)
+	assert.Equal(t, []graph.Node{bNode}, sub.ListNodes())
+	assert.Empty(t, sub.ListRelationships())
+
+	sub, err = grf.Neighbourhood(bNode.GetID(), 2, graph.Outbound)
+	assert.NoError(t, err)
+	assert.Len(t, sub.ListNodes(), 3)
+}
+
+func Test_Graph_Neighbourhood_Standalone(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	sub, err := grf.Neighbourhood(bNode.GetID(), 1, graph.Outbound)
+	assert.NoError(t, err)
+	assert.NoError(t, sub.DeleteNode(aNode.GetID(), graph.CascadeRelationships))
+	_, err = sub.UpsertNode(bobita, puppyType, []byte(`{"power":1}`))
+	assert.NoError(t, err)
+
+	node, err := grf.GetNodeByID(bNode.GetID())
+	assert.NoError(t, err)
+	assert.Equal(t, bobitaBody, node.Body)
+	assert.Len(t, grf.ListRelationships(), 1)
+}
+
+func Test_Graph_Neighbourhood_Errors(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+
+	_, err := grf.Neighbourhood("missing", 1, graph.Outbound)
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+
+	_, err = grf.Neighbourhood(bNode.GetID(), -1, graph.Outbound)
+	assert.Error(t, err)
+}
@@ -2,25 +2,41 @@ package graph
 
 import (
 	"fmt"
+	"strings"
 
 	guuid "github.com/google/uuid"
 )
 
-func newNode(name, label string, body []byte) Node {
+// NamespaceSeparator separates the namespace from the name of a node in a reference. Namespaces can not contain it
+const NamespaceSeparator = ":"
+
+// ParseReference splits a reference to a node into its namespace and name. qualified is false if the reference does not contain a namespace
+func ParseReference(ref string) (namespace, name string, qualified bool) {
+	i := strings.Index(ref, NamespaceSeparator)
+	if i < 0 {
+		return "", ref, false
+	}
+	return ref[:i], ref[i+len(NamespaceSeparator):], true
+}
+
+func newNode(namespace, name, label string, body []byte) Node {
 	return Node{
-		id:    guuid.New().String(),
-		label: label,
-		name:  name,
-		Body:  body,
+		id:        guuid.New().String(),
+		namespace: namespace,
+		label:     label,
+		name:      name,
+		Body:      body,
 	}
 }
 
 // Node represents an item in the graph. It contains the ID of the element, the body and it's relationships to other items
 type Node struct {
-	id    string
-	name  string
-	label string
-	Body  []byte
+	id string
+	// namespace groups nodes coming from the same source, e.g.: an account and region. It is empty for the default namespace
+	namespace string
+	name      string
+	label     string
+	Body      []byte
 }
 
 func (n Node) GetID() string {
@@ -31,10 +47,22 @@ func (n Node) GetName() string {
 	return n.name
 }
 
+func (n Node) GetNamespace() string {
+	return n.namespace
+}
+
+// Reference returns the name of the node, qualified with its namespace unless it is in the default one. It can be parsed back with ParseReference
+func (n Node) Reference() string {
+	if n.namespace == "" {
+		return n.name
+	}
+	return n.namespace + NamespaceSeparator + n.name
+}
+
 func (n Node) GetLabel() string {
 	return n.label
 }
 
 func (n Node) String() string {
-	return fmt.Sprintf("{Asset:%s}", n.name)
+	return fmt.Sprintf("{Asset:%s}", n.Reference())
 }
@@ -0,0 +1,257 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Direction specifies which way relationships are followed when walking the graph
+type Direction int
+
+const (
+	// Outbound follows relationships from their From node to their To node
+	Outbound Direction = iota
+	// Inbound follows relationships backwards, from their To node to their From node
+	Inbound
+	// Both follows relationships either way, treating the graph as undirected
+	Both
+)
+
+// PathOptions restricts which relationships can be followed when looking for paths between nodes, and how far the search can go
+type PathOptions struct {
+	// Labels are the relationship labels that can be followed. If empty, all relationships are followed
+	Labels []string
+	// Direction is the way in which relationships are followed. Defaults to Outbound
+	Direction Direction
+	// MaxDepth is the maximum number of relationships in a path. If 0, paths can have any length
+	MaxDepth int
+	// MaxPaths is the maximum number of paths returned. If 0, all paths are returned
+	MaxPaths int
+}
+
+// Limit identifies one of the limits in PathOptions
+type Limit string
+
+const (
+	LimitMaxDepth Limit = "max depth"
+	LimitMaxPaths Limit = "max paths"
+)
+
+// LimitError is returned when a search stopped before exploring the whole graph because it reached one of its limits.
+// The results found until that point are still returned along with it
+type LimitError struct {
+	Limit Limit
+	Value int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("search stopped after reaching the %s limit of %d; results are incomplete", e.Limit, e.Value)
+}
+
+// ListConnectionsContext returns the chains of relationships that lead from one node to the other, without passing through the same node twice.
+// The search stops when the context is done, returning its error, or when one of the limits in the options is reached, returning a *LimitError along with the chains found so far
+func (g *Graph) ListConnectionsContext(ctx context.Context, from, to Node, opts PathOptions) ([]*ChainLink, error) {
+	g.RLock()
+	defer g.RUnlock()
+	search := &pathSearch{
+		ctx:     ctx,
+		graph:   g,
+		to:      to,
+		opts:    opts,
+		visited: map[string]struct{}{},
+		found:   []*ChainLink{},
+	}
+	if err := search.walk(from.id, 0); err != nil {
+		return search.found, err
+	}
+	if search.truncated {
+		return search.found, &LimitError{Limit: LimitMaxDepth, Value: opts.MaxDepth}
+	}
+	return search.found, nil
+}
+
+// ShortestPath returns a chain with the least amount of relationships leading from one node to the other, using a breadth first search.
+// The search stops when the context is done, returning its error, or when no path was found within the depth limit, returning a *LimitError. The path limit does not apply
+func (g *Graph) ShortestPath(ctx context.Context, from, to Node, opts PathOptions) (*ChainLink, error) {
+	g.RLock()
+	defer g.RUnlock()
+	if !g.store.HasNode(from.id) {
+		return nil, fmt.Errorf("%w; node with id '%s'", ErrNotFound, from.id)
+	}
+	if from.id == to.id {
+		return &ChainLink{node: g.node(from.id)}, nil
+	}
+	// reachedBy holds the hop through which each node was first reached
+	reachedBy := map[string]hop{}
+	visited := map[string]struct{}{from.id: {}}
+	level := []string{from.id}
+	for depth := 0; len(level) > 0; depth++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("search cancelled; %w", err)
+		}
+		if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+			return nil, &LimitError{Limit: LimitMaxDepth, Value: opts.MaxDepth}
+		}
+		next := []string{}
+		for _, current := range level {
+			for _, h := range g.hops(current, opts.Direction, opts.Labels) {
+				if _, ok := visited[h.to]; ok {
+					continue
+				}
+				visited[h.to] = struct{}{}
+				reachedBy[h.to] = h
+				if h.to == to.id {
+					return g.chain(backtrack(to.id, reachedBy)), nil
+				}
+				next = append(next, h.to)
+			}
+		}
+		level = next
+	}
+	return nil, fmt.Errorf("%w; no path from '%s' to '%s'", ErrNotFound, from.name, to.name)
+}
+
+// backtrack returns, in order, the hops leading to the given node, by following the hop through which each node was reached backwards
+func backtrack(nodeID string, reachedBy map[string]hop) []hop {
+	hops := []hop{}
+	for {
+		h, ok := reachedBy[nodeID]
+		if !ok {
+			break
+		}
+		hops = append([]hop{h}, hops...)
+		nodeID = h.from
+	}
+	return hops
+}
+
+// hop is a relationship followed while walking the graph. From and to are the nodes it was followed from and to, which are swapped compared to the relationship when it is followed backwards
+type hop struct {
+	rel  Relationship
+	from string
+	to   string
+}
+
+// hops returns the relationships that can be followed from the node in the given direction. The caller must hold the read lock
+func (g *Graph) hops(nodeID string, direction Direction, labels []string) []hop {
+	hops := []hop{}
+	if direction == Outbound || direction == Both {
+		for _, rel := range g.adjacent(outgoingIndexName, nodeID, labels) {
+			hops = append(hops, hop{rel: rel, from: rel.From, to: rel.To})
+		}
+	}
+	if direction == Inbound || direction == Both {
+		for _, rel := range g.adjacent(incomingIndexName, nodeID, labels) {
+			hops = append(hops, hop{rel: rel, from: rel.To, to: rel.From})
+		}
+	}
+	return hops
+}
+
+// pathSearch holds the state of a depth first search for all the paths between two nodes
+type pathSearch struct {
+	ctx   context.Context
+	graph *Graph
+	to    Node
+	opts  PathOptions
+	// visited holds the nodes on the current path, and steps the relationships followed to reach the current node
+	visited map[string]struct{}
+	steps   []hop
+	found   []*ChainLink
+	// truncated is set when paths were not followed because of the depth limit
+	truncated bool
+}
+
+// walk follows the relationships of the node. The caller must hold the read lock
+func (s *pathSearch) walk(current string, depth int) error {
+	if err := s.ctx.Err(); err != nil {
+		return fmt.Errorf("search cancelled; %w", err)
+	}
+	s.visited[current] = struct{}{}
+	defer delete(s.visited, current)
+	for _, h := range s.graph.hops(current, s.opts.Direction, s.opts.Labels) {
+		// check if the relationship has already been visited. If it has, then go to the next one
+		if _, ok := s.visited[h.to]; ok {
+			continue
+		}
+		if s.opts.MaxDepth > 0 && depth >= s.opts.MaxDepth {
+			s.truncated = true
+			return nil
+		}
+		if h.to == s.to.id {
+			if s.opts.MaxPaths > 0 && len(s.found) >= s.opts.MaxPaths {
+				return &LimitError{Limit: LimitMaxPaths, Value: s.opts.MaxPaths}
+			}
+			s.found = append(s.found, s.graph.chain(append(s.steps, h)))
+			continue
+		}
+		if !s.graph.store.HasNode(h.to) {
+			continue
+		}
+		s.steps = append(s.steps, h)
+		if err := s.walk(h.to, depth+1); err != nil {
+			return err
+		}
+		s.steps = s.steps[:len(s.steps)-1]
+	}
+	return nil
+}
+
+// chain builds the chain made of the given hops. The caller must hold the read lock
+func (g *Graph) chain(hops []hop) *ChainLink {
+	last := hops[len(hops)-1]
+	chain := &ChainLink{node: g.node(last.to)}
+	for i := len(hops) - 1; i >= 0; i-- {
+		chain = &ChainLink{node: g.node(hops[i].from), rel: hops[i].rel, next: chain}
+	}
+	return chain
+}
+
+// TreeNode is a node reached while traversing the graph, along with the relationship through which it was reached and the nodes reached through it
+type TreeNode struct {
+	Node Node
+	// Via is the relationship that was followed to reach the node. It is empty for the node the traversal started from
+	Via      Relationship
+	Children []*TreeNode
+}
+
+// Traverse walks the graph breadth first, starting from the given node and following the relationships allowed by the options, and returns the tree of all nodes reached.
+// Each node appears in the tree only once, at the smallest depth it can be reached. Children are sorted by label, then name.
+// The traversal stops when the context is done, returning its error, or when the depth limit is reached, returning a *LimitError along with the tree built so far. The path limit does not apply
+func (g *Graph) Traverse(ctx context.Context, start Node, opts PathOptions) (*TreeNode, error) {
+	g.RLock()
+	defer g.RUnlock()
+	node, ok := g.store.GetNode(start.id)
+	if !ok {
+		return nil, fmt.Errorf("%w; node with id '%s'", ErrNotFound, start.id)
+	}
+	root := &TreeNode{Node: node}
+	visited := map[string]struct{}{node.id: {}}
+	level := []*TreeNode{root}
+	for depth := 0; len(level) > 0; depth++ {
+		if err := ctx.Err(); err != nil {
+			return root, fmt.Errorf("search cancelled; %w", err)
+		}
+		next := []*TreeNode{}
+		for _, parent := range level {
+			for _, h := range g.hops(parent.Node.id, opts.Direction, opts.Labels) {
+				if _, ok := visited[h.to]; ok {
+					continue
+				}
+				if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+					return root, &LimitError{Limit: LimitMaxDepth, Value: opts.MaxDepth}
+				}
+				visited[h.to] = struct{}{}
+				child := &TreeNode{Node: g.node(h.to), Via: h.rel}
+				parent.Children = append(parent.Children, child)
+				next = append(next, child)
+			}
+			sort.Slice(parent.Children, func(i, j int) bool {
+				return lessNode(parent.Children[i].Node, parent.Children[j].Node)
+			})
+		}
+		level = next
+	}
+	return root, nil
+}
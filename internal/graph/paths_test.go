@@ -0,0 +1,241 @@
+package graph_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_Graph_ShortestPath(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(aNode.GetID(), dNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(bNode.GetID(), dNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	path, err := grf.ShortestPath(context.Background(), bNode, dNode, graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "{Asset:Bobita}->{rel:Bobita-enemies-Smaug}->{Asset:Smaug}", path.String())
+}
+
+func Test_Graph_ShortestPath_Labels(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(aNode.GetID(), dNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(bNode.GetID(), dNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	path, err := grf.ShortestPath(context.Background(), bNode, dNode, graph.PathOptions{Labels: []string{"friends"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "{Asset:Bobita}->{rel:Bobita-friends-Azor}->{Asset:Azor}->{rel:Azor-friends-Smaug}->{Asset:Smaug}", path.String())
+}
+
+func Test_Graph_ShortestPath_NoPath(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	_, err := grf.AddRelationship(aNode.GetID(), bNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	_, err = grf.ShortestPath(context.Background(), bNode, aNode, graph.PathOptions{})
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+}
+
+func Test_Graph_ShortestPath_SameNode(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+
+	path, err := grf.ShortestPath(context.Background(), bNode, bNode, graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "{Asset:Bobita}", path.String())
+}
+
+// newChainGraph creates a graph where every node is connected to all the nodes after it, so the number of paths between the first and the last node grows exponentially
+func newChainGraph(t testing.TB, size int) (*graph.Graph, []graph.Node) {
+	grf := graph.New()
+	nodes := make([]graph.Node, size)
+	for i := range nodes {
+		nodes[i], _ = grf.InsertNode(fmt.Sprintf("item-%d", i), puppyType, []byte{})
+	}
+	for i := range nodes {
+		for j := i + 1; j < size; j++ {
+			_, err := grf.AddRelationship(nodes[i].GetID(), nodes[j].GetID(), "next")
+			assert.NoError(t, err)
+		}
+	}
+	return grf, nodes
+}
+
+func Test_Graph_ListConnectionsContext(t *testing.T) {
+	grf, nodes := newChainGraph(t, 5)
+	chains, err := grf.ListConnectionsContext(context.Background(), nodes[0], nodes[4], graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, chains, 8)
+}
+
+func Test_Graph_ListConnectionsContext_MaxDepth(t *testing.T) {
+	grf, nodes := newChainGraph(t, 5)
+	chains, err := grf.ListConnectionsContext(context.Background(), nodes[0], nodes[4], graph.PathOptions{MaxDepth: 2})
+	limitErr := &graph.LimitError{}
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, graph.LimitMaxDepth, limitErr.Limit)
+	// the direct relationship, and the 3 paths passing through one other node
+	assert.Len(t, chains, 4)
+}
+
+func Test_Graph_ListConnectionsContext_MaxDepthNotReached(t *testing.T) {
+	grf, nodes := newChainGraph(t, 3)
+	chains, err := grf.ListConnectionsContext(context.Background(), nodes[0], nodes[2], graph.PathOptions{MaxDepth: 2})
+	assert.NoError(t, err)
+	assert.Len(t, chains, 2)
+}
+
+func Test_Graph_ListConnectionsContext_MaxPaths(t *testing.T) {
+	grf, nodes := newChainGraph(t, 5)
+	chains, err := grf.ListConnectionsContext(context.Background(), nodes[0], nodes[4], graph.PathOptions{MaxPaths: 3})
+	limitErr := &graph.LimitError{}
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, graph.LimitMaxPaths, limitErr.Limit)
+	assert.Len(t, chains, 3)
+}
+
+func Test_Graph_ListConnectionsContext_Cancelled(t *testing.T) {
+	grf, nodes := newChainGraph(t, 40)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := grf.ListConnectionsContext(ctx, nodes[0], nodes[39], graph.PathOptions{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_Graph_ShortestPath_Limits(t *testing.T) {
+	grf, nodes := newChainGraph(t, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := grf.ShortestPath(ctx, nodes[0], nodes[4], graph.PathOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// every node is connected to the ones after it, so the direct relationship is found within the depth limit
+	path, err := grf.ShortestPath(context.Background(), nodes[0], nodes[4], graph.PathOptions{MaxDepth: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, path.Len())
+
+	// without the relationship leading straight to the last node, it is two relationships away
+	grf, nodes = newChainGraph(t, 3)
+	assert.NoError(t, grf.DeleteRelationship(grf.Outgoing(nodes[0].GetID())[1].ID))
+	limitErr := &graph.LimitError{}
+	_, err = grf.ShortestPath(context.Background(), nodes[0], nodes[2], graph.PathOptions{MaxDepth: 1})
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, graph.LimitMaxDepth, limitErr.Limit)
+}
+
+func Test_Graph_ShortestPath_Inbound(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(aNode.GetID(), dNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	path, err := grf.ShortestPath(context.Background(), dNode, bNode, graph.PathOptions{Direction: graph.Inbound})
+	assert.NoError(t, err)
+	assert.Equal(t, []graph.Node{dNode, aNode, bNode}, path.Nodes())
+	assert.Equal(t, "{Asset:Smaug}->{rel:Azor-enemies-Smaug}->{Asset:Azor}->{rel:Bobita-friends-Azor}->{Asset:Bobita}", path.String())
+
+	_, err = grf.ShortestPath(context.Background(), dNode, bNode, graph.PathOptions{})
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+}
+
+func Test_Graph_ListConnectionsContext_Both(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(dNode.GetID(), aNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	chains, err := grf.ListConnectionsContext(context.Background(), bNode, dNode, graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, chains)
+
+	chains, err = grf.ListConnectionsContext(context.Background(), bNode, dNode, graph.PathOptions{Direction: graph.Both})
+	assert.NoError(t, err)
+	assert.Len(t, chains, 1)
+	assert.Equal(t, []graph.Node{bNode, aNode, dNode}, chains[0].Nodes())
+}
+
+func Test_Graph_Traverse(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	rel1, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	rel2, err := grf.AddRelationship(dNode.GetID(), aNode.GetID(), "enemies")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	tree, err := grf.Traverse(context.Background(), aNode, graph.PathOptions{Direction: graph.Inbound})
+	assert.NoError(t, err)
+	assert.Equal(t, aNode, tree.Node)
+	assert.Len(t, tree.Children, 2)
+	// children are sorted by label, so the dragon comes first
+	assert.Equal(t, dNode, tree.Children[0].Node)
+	assert.Equal(t, rel2, tree.Children[0].Via)
+	assert.Equal(t, bNode, tree.Children[1].Node)
+	assert.Equal(t, rel1, tree.Children[1].Via)
+	// smaug was already reached directly, so it does not show up again under bobita
+	assert.Empty(t, tree.Children[0].Children)
+	assert.Empty(t, tree.Children[1].Children)
+
+	tree, err = grf.Traverse(context.Background(), aNode, graph.PathOptions{Direction: graph.Inbound, Labels: []string{"friends"}})
+	assert.NoError(t, err)
+	assert.Len(t, tree.Children, 1)
+
+	tree, err = grf.Traverse(context.Background(), dNode, graph.PathOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, tree.Children, 2)
+}
+
+func Test_Graph_Traverse_MaxDepth(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, smaugBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	tree, err := grf.Traverse(context.Background(), aNode, graph.PathOptions{Direction: graph.Inbound, MaxDepth: 1})
+	limitErr := &graph.LimitError{}
+	assert.ErrorAs(t, err, &limitErr)
+	assert.Len(t, tree.Children, 1)
+	assert.Empty(t, tree.Children[0].Children)
+}
+
+func Test_Graph_Traverse_NotFound(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	_, err := graph.New().Traverse(context.Background(), bNode, graph.PathOptions{})
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+}
@@ -0,0 +1,271 @@
+package graph
+
+import (
+	"math/bits"
+)
+
+// bits of the hash of a key used at each level of a persistent map
+const (
+	trieBits = 5
+	trieMask = 1<<trieBits - 1
+	// hashBits is the size of the hash; keys whose hashes are equal end up in the same collision node, below the last level
+	hashBits = 64
+)
+
+// editToken marks the trie nodes created by a store since the last view of it was taken. Those nodes are not shared with any view, so they are changed in place.
+// It is not an empty struct, since pointers to distinct empty structs may be equal
+type editToken struct {
+	_ byte
+}
+
+// persistentMap is a hash array mapped trie from strings to values. Copies of the map share its nodes, and changing a copy only copies the nodes
+// on the path to the changed entry, so a view of a store is taken without copying anything, and a change made after it copies at most a node per level.
+// The zero value is an empty map
+type persistentMap struct {
+	root *trieNode
+	size int
+}
+
+// trieNode holds an entry for each bit set in the bitmap, in the order of the bits. Collision nodes hold entries whose keys have the same hash, and no bitmap
+type trieNode struct {
+	edit    *editToken
+	bitmap  uint32
+	entries []trieEntry
+}
+
+// trieEntry is either a key and its value, or a child node if child is set
+type trieEntry struct {
+	key   string
+	value interface{}
+	child *trieNode
+}
+
+// hashKey hashes the key using FNV-1a
+func hashKey(key string) uint64 {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= 1099511628211
+	}
+	return hash
+}
+
+func (m persistentMap) len() int {
+	return m.size
+}
+
+func (m persistentMap) get(key string) (interface{}, bool) {
+	node, hash := m.root, hashKey(key)
+	for shift := uint(0); node != nil; shift += trieBits {
+		if shift >= hashBits {
+			for _, entry := range node.entries {
+				if entry.key == key {
+					return entry.value, true
+				}
+			}
+			return nil, false
+		}
+		bit := uint32(1) << ((hash >> shift) & trieMask)
+		if node.bitmap&bit == 0 {
+			return nil, false
+		}
+		entry := node.entries[node.position(bit)]
+		if entry.child == nil {
+			if entry.key == key {
+				return entry.value, true
+			}
+			return nil, false
+		}
+		node = entry.child
+	}
+	return nil, false
+}
+
+func (m persistentMap) has(key string) bool {
+	_, ok := m.get(key)
+	return ok
+}
+
+// set returns the map with the value stored under the key. The nodes marked with the edit token are changed in place, so the map it was called on
+// must not be used afterwards, unless it was taken before the token was created
+func (m persistentMap) set(edit *editToken, key string, value interface{}) persistentMap {
+	root, added := m.root.set(edit, 0, hashKey(key), key, value)
+	if added {
+		return persistentMap{root: root, size: m.size + 1}
+	}
+	return persistentMap{root: root, size: m.size}
+}
+
+// delete returns the map without the key. Nodes are changed in place as by set
+func (m persistentMap) delete(edit *editToken, key string) persistentMap {
+	root, removed := m.root.delete(edit, 0, hashKey(key), key)
+	if removed {
+		return persistentMap{root: root, size: m.size - 1}
+	}
+	return m
+}
+
+// forEach calls fn for each entry, until fn returns false
+func (m persistentMap) forEach(fn func(key string, value interface{}) bool) {
+	m.root.forEach(fn)
+}
+
+// keys returns the keys of the map
+func (m persistentMap) keys() []string {
+	keys := make([]string, 0, m.size)
+	m.forEach(func(key string, _ interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// position returns where the entry of the bit is found among the entries of the node
+func (n *trieNode) position(bit uint32) int {
+	return bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// editable returns the node itself if it was created with the edit token, or a copy of it marked with the token otherwise
+func (n *trieNode) editable(edit *editToken) *trieNode {
+	if edit != nil && n.edit == edit {
+		return n
+	}
+	entries := make([]trieEntry, len(n.entries), len(n.entries)+1)
+	copy(entries, n.entries)
+	return &trieNode{edit: edit, bitmap: n.bitmap, entries: entries}
+}
+
+// insert adds the entry at the position. The node must be editable
+func (n *trieNode) insert(i int, entry trieEntry) {
+	n.entries = append(n.entries, trieEntry{})
+	copy(n.entries[i+1:], n.entries[i:])
+	n.entries[i] = entry
+}
+
+// remove drops the entry at the position. The node must be editable
+func (n *trieNode) remove(i int) {
+	copy(n.entries[i:], n.entries[i+1:])
+	n.entries[len(n.entries)-1] = trieEntry{}
+	n.entries = n.entries[:len(n.entries)-1]
+}
+
+// set stores the value under the key in the subtree of the node found at the given shift of the hash. It returns the new subtree, and whether the key was added
+func (n *trieNode) set(edit *editToken, shift uint, hash uint64, key string, value interface{}) (*trieNode, bool) {
+	if n == nil {
+		n = &trieNode{edit: edit}
+	}
+	if shift >= hashBits {
+		for i, entry := range n.entries {
+			if entry.key == key {
+				n = n.editable(edit)
+				n.entries[i].value = value
+				return n, false
+			}
+		}
+		n = n.editable(edit)
+		n.entries = append(n.entries, trieEntry{key: key, value: value})
+		return n, true
+	}
+	bit := uint32(1) << ((hash >> shift) & trieMask)
+	i := n.position(bit)
+	if n.bitmap&bit == 0 {
+		n = n.editable(edit)
+		n.insert(i, trieEntry{key: key, value: value})
+		n.bitmap |= bit
+		return n, true
+	}
+	entry := n.entries[i]
+	var child *trieNode
+	added := false
+	switch {
+	case entry.child != nil:
+		child, added = entry.child.set(edit, shift+trieBits, hash, key, value)
+	case entry.key == key:
+		n = n.editable(edit)
+		n.entries[i].value = value
+		return n, false
+	default:
+		// the slot holds another key, so both keys move to a new node one level down
+		child, _ = (*trieNode)(nil).set(edit, shift+trieBits, hashKey(entry.key), entry.key, entry.value)
+		child, added = child.set(edit, shift+trieBits, hash, key, value)
+	}
+	n = n.editable(edit)
+	n.entries[i] = trieEntry{child: child}
+	return n, added
+}
+
+// delete removes the key from the subtree of the node found at the given shift of the hash. It returns the new subtree, which is nil once empty, and whether the key was found
+func (n *trieNode) delete(edit *editToken, shift uint, hash uint64, key string) (*trieNode, bool) {
+	if n == nil {
+		return nil, false
+	}
+	if shift >= hashBits {
+		for i, entry := range n.entries {
+			if entry.key == key {
+				if len(n.entries) == 1 {
+					return nil, true
+				}
+				n = n.editable(edit)
+				n.remove(i)
+				return n, true
+			}
+		}
+		return n, false
+	}
+	bit := uint32(1) << ((hash >> shift) & trieMask)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	i := n.position(bit)
+	entry := n.entries[i]
+	if entry.child == nil {
+		if entry.key != key {
+			return n, false
+		}
+		if len(n.entries) == 1 {
+			return nil, true
+		}
+		n = n.editable(edit)
+		n.remove(i)
+		n.bitmap &^= bit
+		return n, true
+	}
+	child, removed := entry.child.delete(edit, shift+trieBits, hash, key)
+	if !removed {
+		return n, false
+	}
+	switch {
+	case child == nil && len(n.entries) == 1:
+		return nil, true
+	case child == nil:
+		n = n.editable(edit)
+		n.remove(i)
+		n.bitmap &^= bit
+	case len(child.entries) == 1 && child.entries[0].child == nil:
+		// a child left with a single key is replaced by the key, which hashes to the same slot
+		n = n.editable(edit)
+		n.entries[i] = child.entries[0]
+	default:
+		n = n.editable(edit)
+		n.entries[i] = trieEntry{child: child}
+	}
+	return n, true
+}
+
+func (n *trieNode) forEach(fn func(key string, value interface{}) bool) bool {
+	if n == nil {
+		return true
+	}
+	for _, entry := range n.entries {
+		if entry.child != nil {
+			if !entry.child.forEach(fn) {
+				return false
+			}
+			continue
+		}
+		if !fn(entry.key, entry.value) {
+			return false
+		}
+	}
+	return true
+}
@@ -13,19 +13,34 @@ type Relationship struct {
 	FromName string
 	To       string
 	ToName   string
+	// Properties hold the attributes of the relationship, e.g.: the ports or protocol allowed by a rule
+	Properties map[string]string `json:",omitempty"`
 }
 
-func newRelationship(from, to Node, label string) Relationship {
+func newRelationship(from, to Node, label string, properties map[string]string) Relationship {
 	return Relationship{
-		ID:       guuid.New().String(),
-		Label:    label,
-		To:       to.id,
-		ToName:   to.name,
-		From:     from.id,
-		FromName: from.name,
+		ID:         guuid.New().String(),
+		Label:      label,
+		To:         to.id,
+		ToName:     to.name,
+		From:       from.id,
+		FromName:   from.name,
+		Properties: copyProperties(properties),
 	}
 }
 
+// copyProperties returns a copy of the properties, so that a relationship does not share them with anyone else
+func copyProperties(properties map[string]string) map[string]string {
+	if len(properties) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(properties))
+	for k, v := range properties {
+		copied[k] = v
+	}
+	return copied
+}
+
 func (r Relationship) String() string {
 	return fmt.Sprintf("{rel:%s-%s-%s}", r.FromName, r.Label, r.ToName)
 }
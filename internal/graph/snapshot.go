@@ -0,0 +1,146 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// SnapshotVersion is the version of the snapshot format written by WriteSnapshot. Version 2 added the namespaces of the nodes and the properties of the relationships;
+// snapshots of version 1 are still read, as they only lack those
+const SnapshotVersion = 2
+
+// minSnapshotVersion is the oldest version of the snapshot format that can be read
+const minSnapshotVersion = 1
+
+var (
+	ErrUnsupportedSnapshot = errors.New("unsupported snapshot version")
+)
+
+type snapshotNode struct {
+	ID        string `json:"id"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Label     string `json:"label"`
+	Body      []byte `json:"body"`
+}
+
+func toSnapshotNode(node Node) snapshotNode {
+	return snapshotNode{
+		ID:        node.id,
+		Namespace: node.namespace,
+		Name:      node.name,
+		Label:     node.label,
+		Body:      node.Body,
+	}
+}
+
+func (n snapshotNode) node() Node {
+	return Node{
+		id:        n.ID,
+		namespace: n.Namespace,
+		name:      n.Name,
+		label:     n.Label,
+		Body:      n.Body,
+	}
+}
+
+type snapshot struct {
+	Version       int            `json:"version"`
+	Nodes         []snapshotNode `json:"nodes"`
+	Relationships []Relationship `json:"relationships"`
+}
+
+// WriteSnapshot serializes all the nodes and relationships in the graph to the given writer, sorted as by ListNodes and ListRelationships,
+// so that snapshots of the same graph are identical
+func (g *Graph) WriteSnapshot(w io.Writer) error {
+	g.RLock()
+	defer g.RUnlock()
+	nodes := g.listNodes(nil)
+	snap := snapshot{
+		Version:       SnapshotVersion,
+		Nodes:         make([]snapshotNode, 0, len(nodes)),
+		Relationships: make([]Relationship, 0, g.store.CountRelationships()),
+	}
+	for _, node := range nodes {
+		snap.Nodes = append(snap.Nodes, toSnapshotNode(node))
+	}
+	g.store.ForEachRelationship(func(rel Relationship) bool {
+		snap.Relationships = append(snap.Relationships, rel)
+		return true
+	})
+	sortRelationships(snap.Relationships)
+	if err := g.store.Err(); err != nil {
+		return fmt.Errorf("could not read the graph; %w", err)
+	}
+	if err := json.NewEncoder(w).Encode(snap); err != nil {
+		return fmt.Errorf("could not encode snapshot; %w", err)
+	}
+	return nil
+}
+
+// ReadSnapshot creates a new graph, kept in memory, from a snapshot previously written with WriteSnapshot
+func ReadSnapshot(r io.Reader) (*Graph, error) {
+	return ReadSnapshotWithStore(r, NewMemoryStore())
+}
+
+// ReadSnapshotWithStore creates a new graph kept in the given store, which should be empty, from a snapshot previously written with WriteSnapshot
+func ReadSnapshotWithStore(r io.Reader, store Store) (*Graph, error) {
+	snap := snapshot{}
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("could not decode snapshot; %w", err)
+	}
+	if snap.Version < minSnapshotVersion || snap.Version > SnapshotVersion {
+		return nil, fmt.Errorf("%w; got %d, expected %d to %d", ErrUnsupportedSnapshot, snap.Version, minSnapshotVersion, SnapshotVersion)
+	}
+	g, err := NewWithStore(store)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range snap.Nodes {
+		g.putNode(n.node())
+	}
+	for _, rel := range snap.Relationships {
+		if !g.store.HasNode(rel.From) {
+			return nil, fmt.Errorf("%w; relationship %s starts from unknown node '%s'", ErrNotFound, rel.ID, rel.From)
+		}
+		if !g.store.HasNode(rel.To) {
+			return nil, fmt.Errorf("%w; relationship %s points to unknown node '%s'", ErrNotFound, rel.ID, rel.To)
+		}
+		g.putRelationship(rel)
+	}
+	if err := store.Err(); err != nil {
+		return nil, fmt.Errorf("could not store snapshot; %w", err)
+	}
+	return g, nil
+}
+
+// SaveSnapshot writes a snapshot of the graph to the file at the given path
+func (g *Graph) SaveSnapshot(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create snapshot file %s; %w", path, err)
+	}
+	if err := g.WriteSnapshot(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadSnapshot reads a graph, kept in memory, from the snapshot file at the given path
+func LoadSnapshot(path string) (*Graph, error) {
+	return LoadSnapshotWithStore(path, NewMemoryStore())
+}
+
+// LoadSnapshotWithStore reads a graph, kept in the given store, from the snapshot file at the given path
+func LoadSnapshotWithStore(path string, store Store) (*Graph, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open snapshot file %s; %w", path, err)
+	}
+	defer f.Close()
+	return ReadSnapshotWithStore(f, store)
+}
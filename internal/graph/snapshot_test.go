@@ -0,0 +1,108 @@
+package graph_test
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_Graph_Snapshot_RoundTrip(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, []byte{})
+	rel1, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	rel2, err := grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grf.WriteSnapshot(&buf))
+
+	loaded, err := graph.ReadSnapshot(&buf)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, grf.ListNodes(), loaded.ListNodes())
+	assert.ElementsMatch(t, []graph.Relationship{rel1, rel2}, loaded.ListRelationships())
+
+	node, err := loaded.GetNodeByID(dNode.GetID())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, node.Body)
+}
+
+func Test_Graph_Snapshot_File(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "graph.snapshot")
+	assert.NoError(t, grf.SaveSnapshot(path))
+	loaded, err := graph.LoadSnapshot(path)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, grf.ListNodes(), loaded.ListNodes())
+	assert.ElementsMatch(t, grf.ListRelationships(), loaded.ListRelationships())
+}
+
+func Test_Graph_Snapshot_UnsupportedVersion(t *testing.T) {
+	_, err := graph.ReadSnapshot(strings.NewReader(`{"version":999,"nodes":[],"relationships":[]}`))
+	assert.ErrorIs(t, err, graph.ErrUnsupportedSnapshot)
+	_, err = graph.ReadSnapshot(strings.NewReader(`{"version":0,"nodes":[],"relationships":[]}`))
+	assert.ErrorIs(t, err, graph.ErrUnsupportedSnapshot)
+}
+
+func Test_Graph_Snapshot_Version1(t *testing.T) {
+	// version 1 snapshots have neither namespaces nor relationship properties
+	v1 := `{"version":1,"nodes":[{"id":"a","name":"Azor","label":"puppy","body":"e30="},{"id":"b","name":"Bobita","label":"puppy","body":null}],` +
+		`"relationships":[{"ID":"r1","Label":"friends","From":"b","FromName":"Bobita","To":"a","ToName":"Azor"}]}`
+	loaded, err := graph.ReadSnapshot(strings.NewReader(v1))
+	assert.NoError(t, err)
+	assert.Len(t, loaded.ListNodes(graph.FilterNodesByNamespace("")), 2)
+	assert.Equal(t, []graph.Relationship{{ID: "r1", Label: "friends", From: "b", FromName: "Bobita", To: "a", ToName: "Azor"}}, loaded.ListRelationships())
+
+	var buf bytes.Buffer
+	assert.NoError(t, loaded.WriteSnapshot(&buf))
+	assert.Contains(t, buf.String(), fmt.Sprintf(`"version":%d`, graph.SnapshotVersion))
+}
+
+func Test_Graph_Snapshot_DanglingRelationship(t *testing.T) {
+	_, err := graph.ReadSnapshot(strings.NewReader(`{"version":1,"nodes":[],"relationships":[{"ID":"r1","Label":"friends","From":"a","To":"b"}]}`))
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+}
+
+func Test_Graph_Snapshot_Namespaces(t *testing.T) {
+	grf := graph.New()
+	_, err := grf.UpsertNodeInNamespace("kennel", bobita, puppyType, bobitaBody)
+	assert.NoError(t, err)
+	grf.InsertNode(bobita, puppyType, bobitaBody)
+
+	var buf bytes.Buffer
+	assert.NoError(t, grf.WriteSnapshot(&buf))
+
+	loaded, err := graph.ReadSnapshot(&buf)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, grf.ListNodes(), loaded.ListNodes())
+	nodes := loaded.ListNodes(graph.FilterNodesByNamespace("kennel"))
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "kennel:"+bobita, nodes[0].Reference())
+}
+
+func Test_Graph_Snapshot_Deterministic(t *testing.T) {
+	grf := graph.New()
+	for i := 0; i < 20; i++ {
+		from, _ := grf.InsertNode(fmt.Sprintf("puppy-%d", i), puppyType, bobitaBody)
+		to, _ := grf.InsertNode(fmt.Sprintf("dragon-%d", i), dragonType, smaugBody)
+		_, err := grf.AddRelationship(from.GetID(), to.GetID(), "fears")
+		assert.NoError(t, err)
+	}
+	var first, second bytes.Buffer
+	assert.NoError(t, grf.WriteSnapshot(&first))
+	assert.NoError(t, grf.WriteSnapshot(&second))
+	assert.Equal(t, first.String(), second.String())
+}
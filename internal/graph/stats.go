@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Stats describes the shape of a graph
+type Stats struct {
+	Nodes         int `json:"nodes"`
+	Relationships int `json:"relationships"`
+	// NodesByLabel and RelationshipsByLabel hold the number of nodes and relationships having each label
+	NodesByLabel         map[string]int `json:"nodesByLabel"`
+	RelationshipsByLabel map[string]int `json:"relationshipsByLabel"`
+	// Degrees holds the number of nodes having each degree, i.e.: number of relationships starting or ending at the node
+	Degrees map[int]int `json:"degrees"`
+	// Orphans are the nodes without any relationships
+	Orphans []NodeKey `json:"orphans"`
+	// Placeholders are the nodes with an empty body, i.e.: nodes that were referenced, but never loaded
+	Placeholders []NodeKey `json:"placeholders"`
+	// Components are the groups of nodes connected to each other, regardless of the direction of the relationships.
+	// They are sorted by size, largest first, and so are the nodes in each of them
+	Components [][]NodeKey `json:"components"`
+}
+
+// Stats counts the nodes and relationships of the graph, and finds the nodes that might point to gaps in the data loaded into it.
+// If the store fails to read the graph, the error is returned instead of incomplete counts
+func (g *Graph) Stats() (*Stats, error) {
+	g.RLock()
+	defer g.RUnlock()
+	stats := &Stats{
+		Nodes:                g.store.CountNodes(),
+		Relationships:        g.store.CountRelationships(),
+		NodesByLabel:         map[string]int{},
+		RelationshipsByLabel: map[string]int{},
+		Degrees:              map[int]int{},
+		Orphans:              []NodeKey{},
+		Placeholders:         []NodeKey{},
+		Components:           [][]NodeKey{},
+	}
+	g.store.ForEachNode(func(node Node) bool {
+		stats.NodesByLabel[node.label]++
+		degree := g.store.Count(outgoingIndexName, node.id) + g.store.Count(incomingIndexName, node.id)
+		stats.Degrees[degree]++
+		if degree == 0 {
+			stats.Orphans = append(stats.Orphans, keyOf(node))
+		}
+		if len(node.Body) == 0 {
+			stats.Placeholders = append(stats.Placeholders, keyOf(node))
+		}
+		return true
+	})
+	g.store.ForEachRelationship(func(rel Relationship) bool {
+		stats.RelationshipsByLabel[rel.Label]++
+		return true
+	})
+	sortNodeKeys(stats.Orphans)
+	sortNodeKeys(stats.Placeholders)
+	stats.Components = g.connectedComponents()
+	if err := g.store.Err(); err != nil {
+		return nil, fmt.Errorf("could not read the graph; %w", err)
+	}
+	return stats, nil
+}
+
+// connectedComponents returns the keys of the nodes in each weakly connected component of the graph, largest component first.
+// The caller must hold the read lock
+func (g *Graph) connectedComponents() [][]NodeKey {
+	components := [][]NodeKey{}
+	visited := map[string]struct{}{}
+	ids := make([]string, 0, g.store.CountNodes())
+	g.store.ForEachNode(func(node Node) bool {
+		ids = append(ids, node.id)
+		return true
+	})
+	for _, id := range ids {
+		if _, ok := visited[id]; ok {
+			continue
+		}
+		visited[id] = struct{}{}
+		component := []NodeKey{}
+		queue := []string{id}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			component = append(component, keyOf(g.node(current)))
+			for _, h := range g.hops(current, Both, nil) {
+				if _, ok := visited[h.to]; ok {
+					continue
+				}
+				if !g.store.HasNode(h.to) {
+					continue
+				}
+				visited[h.to] = struct{}{}
+				queue = append(queue, h.to)
+			}
+		}
+		sortNodeKeys(component)
+		components = append(components, component)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		if len(components[i]) != len(components[j]) {
+			return len(components[i]) > len(components[j])
+		}
+		return lessNodeKey(components[i][0], components[j][0])
+	})
+	return components
+}
@@ -0,0 +1,45 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_Graph_Stats(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	dNode, _ := grf.InsertNode(smaug, dragonType, []byte{})
+	grf.InsertNode("Rex", puppyType, []byte(`{}`))
+	_, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(aNode.GetID(), bNode.GetID(), "friends")
+	assert.NoError(t, err)
+	_, err = grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+	assert.NoError(t, err)
+
+	stats, err := grf.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, stats.Nodes)
+	assert.Equal(t, 3, stats.Relationships)
+	assert.Equal(t, map[string]int{puppyType: 3, dragonType: 1}, stats.NodesByLabel)
+	assert.Equal(t, map[string]int{"friends": 2, "enemies": 1}, stats.RelationshipsByLabel)
+	assert.Equal(t, map[int]int{0: 1, 1: 1, 2: 1, 3: 1}, stats.Degrees)
+	assert.Equal(t, []graph.NodeKey{{Label: puppyType, Name: "Rex"}}, stats.Orphans)
+	assert.Equal(t, []graph.NodeKey{{Label: dragonType, Name: smaug}}, stats.Placeholders)
+	assert.Equal(t, [][]graph.NodeKey{
+		{{Label: dragonType, Name: smaug}, {Label: puppyType, Name: azor}, {Label: puppyType, Name: bobita}},
+		{{Label: puppyType, Name: "Rex"}},
+	}, stats.Components)
+}
+
+func Test_Graph_Stats_Empty(t *testing.T) {
+	stats, err := graph.New().Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.Nodes)
+	assert.Empty(t, stats.Degrees)
+	assert.Empty(t, stats.Components)
+}
@@ -0,0 +1,271 @@
+package graph
+
+// Store holds the nodes and relationships of a graph, along with the indexes used to look them up.
+// The graph decides what is indexed and keeps the indexes up to date; the store only needs to keep the sets of IDs stored under each key of an index.
+// Stores are not safe for concurrent use, since the graph holds its lock while using them; a store and its views can be used concurrently though.
+// A store that can fail, e.g. because it reads from a file, remembers the first error it runs into and reports it through Err
+type Store interface {
+	// PutNode adds the node, or replaces the node having the same ID
+	PutNode(node Node)
+	GetNode(id string) (Node, bool)
+	HasNode(id string) bool
+	DeleteNode(id string)
+	// ForEachNode calls fn for each node, until fn returns false
+	ForEachNode(fn func(node Node) bool)
+	CountNodes() int
+
+	// PutRelationship adds the relationship, or replaces the relationship having the same ID
+	PutRelationship(rel Relationship)
+	GetRelationship(id string) (Relationship, bool)
+	DeleteRelationship(id string)
+	// ForEachRelationship calls fn for each relationship, until fn returns false
+	ForEachRelationship(fn func(rel Relationship) bool)
+	CountRelationships() int
+
+	// Index adds the ID to the set stored under the key of the named index, and Unindex removes it
+	Index(index, key, id string)
+	Unindex(index, key, id string)
+	// Lookup returns the IDs stored under any of the keys of the index, without duplicates
+	Lookup(index string, keys ...string) []string
+	// Indexed returns true if the ID is stored under the key of the index
+	Indexed(index, key, id string) bool
+	// Count returns the number of IDs stored under the key of the index
+	Count(index, key string) int
+	// Keys returns the keys of the index that have at least one ID stored under them
+	Keys(index string) []string
+
+	// View returns a read-only view of the store as it is now. Changes made to the store afterwards are not seen through the view,
+	// and changes made through the view fail with ErrReadOnly
+	View() Store
+	// Err returns the first error the store ran into, if any. Reads that fail return nothing, and writes that fail are lost
+	Err() error
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// names of the indexes the graph keeps in its store
+const (
+	labelIndexName     = "label"
+	nameIndexName      = "name"
+	namespaceIndexName = "namespace"
+	outgoingIndexName  = "outgoing"
+	incomingIndexName  = "incoming"
+	// fieldIndexPrefix is followed by the label and path of an indexed field
+	fieldIndexPrefix = "field:"
+)
+
+// memoryStore keeps everything in persistent maps, which it shares with its views. It is the store used by New
+type memoryStore struct {
+	indexSet
+	nodes         persistentMap
+	relationships persistentMap
+	// edit marks the parts of the maps created since the last view was taken, which are changed in place; the parts shared with views are copied before being changed
+	edit *editToken
+	// readOnly is set for views, which hold the error of the first change made through them
+	readOnly bool
+	err      error
+}
+
+// NewMemoryStore creates a store that keeps the graph in memory
+func NewMemoryStore() Store {
+	return &memoryStore{edit: &editToken{}}
+}
+
+// writable returns false for views, which can not be changed
+func (s *memoryStore) writable() bool {
+	if s.readOnly {
+		if s.err == nil {
+			s.err = ErrReadOnly
+		}
+		return false
+	}
+	return true
+}
+
+func (s *memoryStore) PutNode(node Node) {
+	if s.writable() {
+		s.nodes = s.nodes.set(s.edit, node.id, node)
+	}
+}
+
+func (s *memoryStore) GetNode(id string) (Node, bool) {
+	node, ok := s.nodes.get(id)
+	if !ok {
+		return Node{}, false
+	}
+	return node.(Node), true
+}
+
+func (s *memoryStore) HasNode(id string) bool {
+	return s.nodes.has(id)
+}
+
+func (s *memoryStore) DeleteNode(id string) {
+	if s.writable() {
+		s.nodes = s.nodes.delete(s.edit, id)
+	}
+}
+
+func (s *memoryStore) ForEachNode(fn func(node Node) bool) {
+	s.nodes.forEach(func(_ string, node interface{}) bool {
+		return fn(node.(Node))
+	})
+}
+
+func (s *memoryStore) CountNodes() int {
+	return s.nodes.len()
+}
+
+// PutRelationship keeps its own copy of the properties, and GetRelationship and ForEachRelationship hand out copies of them,
+// so that changing the properties of a relationship read from the store changes neither the store nor its views
+func (s *memoryStore) PutRelationship(rel Relationship) {
+	if s.writable() {
+		rel.Properties = copyProperties(rel.Properties)
+		s.relationships = s.relationships.set(s.edit, rel.ID, rel)
+	}
+}
+
+func (s *memoryStore) GetRelationship(id string) (Relationship, bool) {
+	stored, ok := s.relationships.get(id)
+	if !ok {
+		return Relationship{}, false
+	}
+	rel := stored.(Relationship)
+	rel.Properties = copyProperties(rel.Properties)
+	return rel, true
+}
+
+func (s *memoryStore) DeleteRelationship(id string) {
+	if s.writable() {
+		s.relationships = s.relationships.delete(s.edit, id)
+	}
+}
+
+func (s *memoryStore) ForEachRelationship(fn func(rel Relationship) bool) {
+	s.relationships.forEach(func(_ string, stored interface{}) bool {
+		rel := stored.(Relationship)
+		rel.Properties = copyProperties(rel.Properties)
+		return fn(rel)
+	})
+}
+
+func (s *memoryStore) CountRelationships() int {
+	return s.relationships.len()
+}
+
+func (s *memoryStore) Index(index, key, id string) {
+	if s.writable() {
+		s.indexSet.add(s.edit, index, key, id)
+	}
+}
+
+func (s *memoryStore) Unindex(index, key, id string) {
+	if s.writable() {
+		s.indexSet.remove(s.edit, index, key, id)
+	}
+}
+
+// View shares the maps with the view, so that taking it does not copy anything. The store starts a new edit token,
+// so that its next changes copy the parts of the maps they touch instead of changing the ones shared with the view
+func (s *memoryStore) View() Store {
+	if s.readOnly {
+		return s
+	}
+	s.edit = &editToken{}
+	return &memoryStore{
+		indexSet:      s.indexSet,
+		nodes:         s.nodes,
+		relationships: s.relationships,
+		readOnly:      true,
+	}
+}
+
+func (s *memoryStore) Err() error {
+	return s.err
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+// indexSet keeps the indexes of a store in memory: the sets of IDs stored under each key, by the name of the index.
+// The indexes, the keys of each index and the sets of IDs are all persistent maps, so that they can be shared with views as the nodes and relationships are
+type indexSet struct {
+	indexes persistentMap
+}
+
+// add stores the ID under the key of the index. Nodes of the maps are changed in place as by persistentMap.set
+func (s *indexSet) add(edit *editToken, index, key, id string) {
+	keys := s.keys(index)
+	ids := idSet(keys, key)
+	if ids.has(id) {
+		return
+	}
+	keys = keys.set(edit, key, ids.set(edit, id, struct{}{}))
+	s.indexes = s.indexes.set(edit, index, keys)
+}
+
+// remove drops the ID from the key of the index, and the key once it holds no IDs
+func (s *indexSet) remove(edit *editToken, index, key, id string) {
+	keys := s.keys(index)
+	ids := idSet(keys, key)
+	if !ids.has(id) {
+		return
+	}
+	ids = ids.delete(edit, id)
+	if ids.len() == 0 {
+		keys = keys.delete(edit, key)
+	} else {
+		keys = keys.set(edit, key, ids)
+	}
+	s.indexes = s.indexes.set(edit, index, keys)
+}
+
+// keys returns the keys of the index, mapped to their sets of IDs
+func (s indexSet) keys(index string) persistentMap {
+	keys, ok := s.indexes.get(index)
+	if !ok {
+		return persistentMap{}
+	}
+	return keys.(persistentMap)
+}
+
+// idSet returns the set of IDs stored under the key
+func idSet(keys persistentMap, key string) persistentMap {
+	ids, ok := keys.get(key)
+	if !ok {
+		return persistentMap{}
+	}
+	return ids.(persistentMap)
+}
+
+func (s indexSet) Lookup(index string, keys ...string) []string {
+	indexKeys := s.keys(index)
+	if len(keys) == 1 {
+		return idSet(indexKeys, keys[0]).keys()
+	}
+	ids := []string{}
+	seen := map[string]struct{}{}
+	for _, key := range keys {
+		idSet(indexKeys, key).forEach(func(id string, _ interface{}) bool {
+			if _, ok := seen[id]; !ok {
+				seen[id] = struct{}{}
+				ids = append(ids, id)
+			}
+			return true
+		})
+	}
+	return ids
+}
+
+func (s indexSet) Indexed(index, key, id string) bool {
+	return idSet(s.keys(index), key).has(id)
+}
+
+func (s indexSet) Count(index, key string) int {
+	return idSet(s.keys(index), key).len()
+}
+
+func (s indexSet) Keys(index string) []string {
+	return s.keys(index).keys()
+}
@@ -0,0 +1,53 @@
+package graph_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_Graph_Stores(t *testing.T) {
+	stores := map[string]func() (graph.Store, error){
+		"memory": func() (graph.Store, error) { return graph.NewMemoryStore(), nil },
+		"disk":   func() (graph.Store, error) { return graph.OpenDiskStore(filepath.Join(t.TempDir(), "graph.store")) },
+		"temp":   func() (graph.Store, error) { return graph.NewTempDiskStore(t.TempDir()) },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store, err := newStore()
+			assert.NoError(t, err)
+			grf, err := graph.NewWithStore(store)
+			assert.NoError(t, err)
+			grf.IndexField(puppyType, "power")
+
+			bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+			aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+			dNode, _ := grf.InsertNode(smaug, dragonType, []byte{})
+			_, err = grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+			assert.NoError(t, err)
+			_, err = grf.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies")
+			assert.NoError(t, err)
+
+			_, err = grf.UpsertNode(smaug, dragonType, smaugBody)
+			assert.NoError(t, err)
+			node, err := grf.GetNodeByID(dNode.GetID())
+			assert.NoError(t, err)
+			assert.Equal(t, smaugBody, node.Body)
+
+			assert.Len(t, grf.ListNodes(graph.FilterNodesByLabel(puppyType)), 2)
+			assert.Equal(t, []graph.Node{aNode}, grf.ListNodes(graph.FilterNodesByField("power", 457), graph.FilterNodesByLabel(puppyType)))
+			assert.Len(t, grf.ListRelationships(graph.FilterRelByFrom(dNode.GetID())), 1)
+
+			assert.ErrorIs(t, grf.DeleteNode(bNode.GetID(), graph.RejectDangling), graph.ErrHasRelationships)
+			assert.NoError(t, grf.DeleteNode(bNode.GetID(), graph.CascadeRelationships))
+			assert.Len(t, grf.ListNodes(), 2)
+			assert.Empty(t, grf.ListRelationships())
+
+			assert.NoError(t, grf.Err())
+			assert.NoError(t, grf.Close())
+		})
+	}
+}
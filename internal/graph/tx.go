@@ -0,0 +1,153 @@
+package graph
+
+// Tx is used to change the graph from within Batch. All the changes made through it are applied together, or not at all.
+// A Tx can only be used inside the function given to Batch
+type Tx struct {
+	graph *Graph
+	// undo holds, in the order the changes were made, the functions that revert them
+	undo []func()
+	// events holds the changes to publish once the batch succeeds
+	events []Event
+}
+
+// Batch runs the function while holding the write lock of the graph, so that no one else sees the changes it makes through the transaction until it returns.
+// If the function returns an error or panics, all its changes are rolled back. Otherwise, the changes are published to the subscribers once the function returns.
+// The function must only use the transaction to access the graph; calling the methods of the graph itself from it blocks forever
+func (g *Graph) Batch(fn func(tx *Tx) error) error {
+	if g.readOnly {
+		return ErrReadOnly
+	}
+	g.Lock()
+	defer g.Unlock()
+	tx := &Tx{graph: g}
+	g.batch = tx
+	committed := false
+	defer func() {
+		g.batch = nil
+		if !committed {
+			tx.rollback()
+			return
+		}
+		for _, event := range tx.events {
+			g.publish(event)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// rollback reverts the changes made through the transaction, newest first. The caller must hold the write lock
+func (tx *Tx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = nil
+	tx.events = nil
+}
+
+// InsertNode adds a new node to the graph. As with Graph.InsertNode, ErrInvalidBody is returned if the body does not match the type registered for the label
+func (tx *Tx) InsertNode(name, label string, body []byte) (Node, error) {
+	node, err := tx.graph.insertNode("", name, label, body)
+	if err != nil {
+		return node, err
+	}
+	tx.undo = append(tx.undo, func() {
+		tx.graph.removeNode(node.id)
+	})
+	return node, nil
+}
+
+// UpsertNode inserts or updates the node with the given name and label, the same way Graph.UpsertNode does
+func (tx *Tx) UpsertNode(name, label string, body []byte) (Node, error) {
+	return tx.UpsertNodeInNamespace("", name, label, body)
+}
+
+// UpsertNodeInNamespace inserts or updates the node with the given namespace, name and label, the same way Graph.UpsertNodeInNamespace does
+func (tx *Tx) UpsertNodeInNamespace(namespace, name, label string, body []byte) (Node, error) {
+	previous := map[string]Node{}
+	for _, id := range tx.graph.store.Lookup(nameIndexName, name) {
+		previous[id] = tx.graph.node(id)
+	}
+	node, err := tx.graph.upsertNode(namespace, name, label, body)
+	if err != nil {
+		return node, err
+	}
+	if prev, ok := previous[node.id]; ok {
+		tx.undo = append(tx.undo, func() {
+			tx.graph.replaceNode(prev)
+		})
+		return node, nil
+	}
+	tx.undo = append(tx.undo, func() {
+		tx.graph.removeNode(node.id)
+	})
+	return node, nil
+}
+
+// DeleteNode removes the node from the graph, the same way Graph.DeleteNode does
+func (tx *Tx) DeleteNode(id string, mode DeleteMode) error {
+	node := tx.graph.node(id)
+	rels := append(tx.graph.adjacent(outgoingIndexName, id, nil), tx.graph.adjacent(incomingIndexName, id, nil)...)
+	if err := tx.graph.deleteNode(id, mode); err != nil {
+		return err
+	}
+	tx.undo = append(tx.undo, func() {
+		tx.graph.putNode(node)
+		for _, rel := range rels {
+			tx.graph.putRelationship(rel)
+		}
+	})
+	return nil
+}
+
+// AddRelationship establishes a unidirectional relationship between the two nodes
+func (tx *Tx) AddRelationship(fromID, toID, label string) (Relationship, error) {
+	return tx.AddRelationshipWithProperties(fromID, toID, label, nil)
+}
+
+// AddRelationshipWithProperties establishes a unidirectional relationship between the two nodes, the same way Graph.AddRelationshipWithProperties does
+func (tx *Tx) AddRelationshipWithProperties(fromID, toID, label string, properties map[string]string) (Relationship, error) {
+	rel, err := tx.graph.addRelationship(fromID, toID, label, properties)
+	if err != nil {
+		return rel, err
+	}
+	tx.undo = append(tx.undo, func() {
+		tx.graph.removeRelationship(rel.ID)
+	})
+	return rel, nil
+}
+
+// DeleteRelationship removes the relationship from the graph. The nodes it connects are not changed
+func (tx *Tx) DeleteRelationship(id string) error {
+	rel, _ := tx.graph.store.GetRelationship(id)
+	if err := tx.graph.deleteRelationship(id); err != nil {
+		return err
+	}
+	tx.undo = append(tx.undo, func() {
+		tx.graph.putRelationship(rel)
+	})
+	return nil
+}
+
+// GetNodeByID returns the node that has the given ID, including the changes made so far in the transaction
+func (tx *Tx) GetNodeByID(id string) (Node, error) {
+	return tx.graph.getNodeByID(id)
+}
+
+// ListNodes returns the nodes that match all the where clauses, including the changes made so far in the transaction
+func (tx *Tx) ListNodes(where ...NodeFilter) []Node {
+	return tx.graph.listNodes(where)
+}
+
+// Outgoing returns the relationships starting from the given node. If labels are provided, only the relationships having one of the labels are returned
+func (tx *Tx) Outgoing(nodeID string, labels ...string) []Relationship {
+	return tx.graph.adjacent(outgoingIndexName, nodeID, labels)
+}
+
+// Incoming returns the relationships pointing to the given node. If labels are provided, only the relationships having one of the labels are returned
+func (tx *Tx) Incoming(nodeID string, labels ...string) []Relationship {
+	return tx.graph.adjacent(incomingIndexName, nodeID, labels)
+}
@@ -0,0 +1,88 @@
+package graph_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+func Test_Graph_Batch_Commit(t *testing.T) {
+	grf := graph.New()
+	var bNode, aNode graph.Node
+	err := grf.Batch(func(tx *graph.Tx) error {
+		bNode, _ = tx.InsertNode(bobita, puppyType, bobitaBody)
+		var err error
+		aNode, err = tx.UpsertNode(azor, puppyType, azorBody)
+		if err != nil {
+			return err
+		}
+		// the changes made so far are visible inside the transaction
+		assert.Len(t, tx.ListNodes(graph.FilterNodesByLabel(puppyType)), 2)
+		_, err = tx.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+		return err
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []graph.Node{bNode, aNode}, grf.ListNodes())
+	assert.Len(t, grf.Outgoing(bNode.GetID()), 1)
+}
+
+func Test_Graph_Batch_Rollback(t *testing.T) {
+	grf := graph.New()
+	bNode, _ := grf.InsertNode(bobita, puppyType, bobitaBody)
+	aNode, _ := grf.InsertNode(azor, puppyType, azorBody)
+	rel, err := grf.AddRelationship(bNode.GetID(), aNode.GetID(), "friends")
+	assert.NoError(t, err)
+
+	err = grf.Batch(func(tx *graph.Tx) error {
+		dNode, _ := tx.InsertNode(smaug, dragonType, smaugBody)
+		if _, err := tx.AddRelationship(dNode.GetID(), bNode.GetID(), "enemies"); err != nil {
+			return err
+		}
+		if _, err := tx.UpsertNode(bobita, puppyType, []byte(`{"power":1}`)); err != nil {
+			return err
+		}
+		if err := tx.DeleteRelationship(rel.ID); err != nil {
+			return err
+		}
+		if err := tx.DeleteNode(aNode.GetID(), graph.CascadeRelationships); err != nil {
+			return err
+		}
+		_, err := tx.AddRelationship(dNode.GetID(), "missing", "enemies")
+		return err
+	})
+	assert.ErrorIs(t, err, graph.ErrNotFound)
+
+	assert.ElementsMatch(t, []graph.Node{bNode, aNode}, grf.ListNodes())
+	assert.Empty(t, grf.ListNodes(graph.FilterNodesByName(smaug)))
+	assert.Equal(t, []graph.Relationship{rel}, grf.ListRelationships())
+	assert.Equal(t, []graph.Relationship{rel}, grf.Incoming(aNode.GetID()))
+	node, err := grf.GetNodeByID(bNode.GetID())
+	assert.NoError(t, err)
+	assert.Equal(t, bobitaBody, node.Body)
+}
+
+func Test_Graph_Batch_Panic(t *testing.T) {
+	grf := graph.New()
+	assert.Panics(t, func() {
+		_ = grf.Batch(func(tx *graph.Tx) error {
+			tx.InsertNode(bobita, puppyType, bobitaBody)
+			panic("failed")
+		})
+	})
+	assert.Empty(t, grf.ListNodes())
+}
+
+func Test_Graph_Batch_Error(t *testing.T) {
+	grf := graph.New()
+	errFailed := errors.New("failed")
+	err := grf.Batch(func(tx *graph.Tx) error {
+		tx.InsertNode(bobita, puppyType, bobitaBody)
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+	assert.Empty(t, grf.ListNodes())
+	assert.Empty(t, grf.ListNodes(graph.FilterNodesByName(bobita)))
+}
@@ -0,0 +1,129 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenSymbol
+)
+
+// token is a piece of a query. text holds the identifier, the unquoted string, the number or the symbol, and pos where it starts in the query
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return fmt.Sprintf("%q", t.text)
+	default:
+		return fmt.Sprintf("'%s'", t.text)
+	}
+}
+
+// is returns true if the token is the given symbol, or the given keyword regardless of its case
+func (t token) is(text string) bool {
+	switch t.kind {
+	case tokenSymbol:
+		return t.text == text
+	case tokenIdent:
+		return strings.EqualFold(t.text, text)
+	default:
+		return false
+	}
+}
+
+// twoCharSymbols are checked before single characters, so that `<=` is not read as `<` followed by `=`
+var twoCharSymbols = []string{"<>", "<=", ">="}
+
+const symbols = "()[]{}:,.-<>=|"
+
+// tokenize splits the query into tokens, ending with a tokenEOF
+func tokenize(input string) ([]token, error) {
+	tokens := []token{}
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			text, next, err := readString(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i = next
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: start})
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		default:
+			symbol := ""
+			for _, s := range twoCharSymbols {
+				if strings.HasPrefix(string(runes[i:]), s) {
+					symbol = s
+					break
+				}
+			}
+			if symbol == "" && strings.ContainsRune(symbols, r) {
+				symbol = string(r)
+			}
+			if symbol == "" {
+				return nil, fmt.Errorf("%w; unexpected character '%c' at position %d", ErrSyntax, r, i)
+			}
+			tokens = append(tokens, token{kind: tokenSymbol, text: symbol, pos: i})
+			i += len([]rune(symbol))
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// readString reads the quoted string starting at the given position, and returns its unquoted text along with the position following it
+func readString(runes []rune, start int) (string, int, error) {
+	quote := runes[start]
+	var text strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case quote:
+			return text.String(), i + 1, nil
+		case '\\':
+			i++
+			if i == len(runes) {
+				break
+			}
+			switch runes[i] {
+			case 'n':
+				text.WriteRune('\n')
+			case 't':
+				text.WriteRune('\t')
+			default:
+				text.WriteRune(runes[i])
+			}
+		default:
+			text.WriteRune(runes[i])
+		}
+	}
+	return "", 0, fmt.Errorf("%w; string starting at position %d is not closed", ErrSyntax, start)
+}
@@ -0,0 +1,401 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+// nodePattern matches nodes having the label, if set, and a body holding the given top level fields
+type nodePattern struct {
+	variable   string
+	label      string
+	properties map[string]interface{}
+}
+
+// relPattern matches relationships having one of the labels, if any are set, and the given properties, followed in the given direction
+type relPattern struct {
+	variable   string
+	labels     []string
+	properties map[string]interface{}
+	direction  graph.Direction
+}
+
+// operand is either a literal value, a variable, or a field of a variable (e.g.: vm.vpcID)
+type operand struct {
+	literal   interface{}
+	isLiteral bool
+	variable  string
+	path      []string
+}
+
+func (o operand) String() string {
+	if o.isLiteral {
+		return fmt.Sprint(o.literal)
+	}
+	return strings.Join(append([]string{o.variable}, o.path...), ".")
+}
+
+// condition compares two operands in the WHERE clause
+type condition struct {
+	left  operand
+	op    string
+	right operand
+}
+
+// returnItem is a column of the result
+type returnItem struct {
+	value operand
+	name  string
+}
+
+// parser reads a query from its tokens
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given symbol or keyword
+func (p *parser) accept(text string) bool {
+	if p.peek().is(text) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.unexpected(fmt.Sprintf("'%s'", text))
+	}
+	return nil
+}
+
+func (p *parser) expectIdent(what string) (string, error) {
+	t := p.peek()
+	if t.kind != tokenIdent {
+		return "", p.unexpected(what)
+	}
+	p.advance()
+	return t.text, nil
+}
+
+func (p *parser) unexpected(expected string) error {
+	t := p.peek()
+	return fmt.Errorf("%w; expected %s at position %d, found %s", ErrSyntax, expected, t.pos, t)
+}
+
+// parseQuery reads MATCH pattern [WHERE condition [AND condition]...] RETURN [DISTINCT] item [AS name] [, item [AS name]]... [LIMIT n]
+func (p *parser) parseQuery() (*Query, error) {
+	q := &Query{}
+	if err := p.expect("MATCH"); err != nil {
+		return nil, err
+	}
+	if err := p.parsePattern(q); err != nil {
+		return nil, err
+	}
+	if p.accept("WHERE") {
+		for {
+			cond, err := p.parseCondition()
+			if err != nil {
+				return nil, err
+			}
+			q.where = append(q.where, cond)
+			if !p.accept("AND") {
+				break
+			}
+		}
+	}
+	if err := p.expect("RETURN"); err != nil {
+		return nil, err
+	}
+	q.distinct = p.accept("DISTINCT")
+	for {
+		value, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		item := returnItem{value: value, name: value.String()}
+		if p.accept("AS") {
+			if item.name, err = p.expectIdent("a column name"); err != nil {
+				return nil, err
+			}
+		}
+		q.returns = append(q.returns, item)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if p.accept("LIMIT") {
+		t := p.peek()
+		limit, err := strconv.Atoi(t.text)
+		if t.kind != tokenNumber || err != nil {
+			return nil, p.unexpected("a whole number")
+		}
+		p.advance()
+		q.limit = limit
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.unexpected("the end of the query")
+	}
+	return q, nil
+}
+
+// parsePattern reads a chain of nodes connected by relationships, e.g.: (vm:vm)-[:using]->(i:interface)
+func (p *parser) parsePattern(q *Query) error {
+	node, err := p.parseNode()
+	if err != nil {
+		return err
+	}
+	q.nodes = append(q.nodes, node)
+	for p.peek().is("-") || p.peek().is("<") {
+		rel, err := p.parseRelationship()
+		if err != nil {
+			return err
+		}
+		node, err := p.parseNode()
+		if err != nil {
+			return err
+		}
+		q.rels = append(q.rels, rel)
+		q.nodes = append(q.nodes, node)
+	}
+	return nil
+}
+
+// parseNode reads (variable:label {field: value, ...}), where each part is optional
+func (p *parser) parseNode() (nodePattern, error) {
+	node := nodePattern{}
+	if err := p.expect("("); err != nil {
+		return node, err
+	}
+	if p.peek().kind == tokenIdent {
+		node.variable = p.advance().text
+	}
+	if p.accept(":") {
+		label, err := p.expectIdent("a label")
+		if err != nil {
+			return node, err
+		}
+		node.label = label
+	}
+	if p.peek().is("{") {
+		properties, err := p.parseProperties()
+		if err != nil {
+			return node, err
+		}
+		node.properties = properties
+	}
+	return node, p.expect(")")
+}
+
+// parseRelationship reads -[variable:label|label {key: value, ...}]->, or the same pointing left, or without any arrow to follow relationships either way.
+// The part between brackets is optional, e.g.: -->
+func (p *parser) parseRelationship() (relPattern, error) {
+	rel := relPattern{direction: graph.Both}
+	left := p.accept("<")
+	if err := p.expect("-"); err != nil {
+		return rel, err
+	}
+	if p.accept("[") {
+		if p.peek().kind == tokenIdent {
+			rel.variable = p.advance().text
+		}
+		if p.accept(":") {
+			for {
+				label, err := p.expectIdent("a relationship label")
+				if err != nil {
+					return rel, err
+				}
+				rel.labels = append(rel.labels, label)
+				if !p.accept("|") {
+					break
+				}
+			}
+		}
+		if p.peek().is("{") {
+			properties, err := p.parseProperties()
+			if err != nil {
+				return rel, err
+			}
+			rel.properties = properties
+		}
+		if err := p.expect("]"); err != nil {
+			return rel, err
+		}
+	}
+	if err := p.expect("-"); err != nil {
+		return rel, err
+	}
+	right := p.accept(">")
+	switch {
+	case left && right:
+		return rel, fmt.Errorf("%w; relationship ending at position %d points both ways", ErrSyntax, p.peek().pos)
+	case left:
+		rel.direction = graph.Inbound
+	case right:
+		rel.direction = graph.Outbound
+	}
+	return rel, nil
+}
+
+// parseProperties reads {key: value, ...}
+func (p *parser) parseProperties() (map[string]interface{}, error) {
+	properties := map[string]interface{}{}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	if p.accept("}") {
+		return properties, nil
+	}
+	for {
+		key, err := p.expectIdent("a field name")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		properties[key] = value
+		if !p.accept(",") {
+			break
+		}
+	}
+	return properties, p.expect("}")
+}
+
+// parseCondition reads operand op operand
+func (p *parser) parseCondition() (condition, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return condition{}, err
+	}
+	t := p.peek()
+	op := ""
+	for _, candidate := range operators {
+		if t.is(candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return condition{}, p.unexpected("a comparison (=, <>, <, <=, >, >=, CONTAINS)")
+	}
+	p.advance()
+	right, err := p.parseOperand()
+	if err != nil {
+		return condition{}, err
+	}
+	return condition{left: left, op: op, right: right}, nil
+}
+
+// parseOperand reads a literal, or a variable followed by the path to one of its fields
+func (p *parser) parseOperand() (operand, error) {
+	t := p.peek()
+	if t.kind != tokenIdent || isKeywordLiteral(t) {
+		value, err := p.parseLiteral()
+		if err != nil {
+			return operand{}, err
+		}
+		return operand{literal: value, isLiteral: true}, nil
+	}
+	o := operand{variable: p.advance().text}
+	for p.accept(".") {
+		field, err := p.expectIdent("a field name")
+		if err != nil {
+			return operand{}, err
+		}
+		o.path = append(o.path, field)
+	}
+	return o, nil
+}
+
+func isKeywordLiteral(t token) bool {
+	return t.is("true") || t.is("false") || t.is("null")
+}
+
+// parseLiteral reads a string, a number, true, false or null
+func (p *parser) parseLiteral() (interface{}, error) {
+	negative := p.accept("-")
+	t := p.peek()
+	switch {
+	case t.kind == tokenNumber:
+		p.advance()
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w; invalid number %s at position %d", ErrSyntax, t.text, t.pos)
+		}
+		if negative {
+			value = -value
+		}
+		return value, nil
+	case negative:
+		return nil, p.unexpected("a number")
+	case t.kind == tokenString:
+		p.advance()
+		return t.text, nil
+	case t.is("true"):
+		p.advance()
+		return true, nil
+	case t.is("false"):
+		p.advance()
+		return false, nil
+	case t.is("null"):
+		p.advance()
+		return nil, nil
+	}
+	return nil, p.unexpected("a value")
+}
+
+// check makes sure the variables used in the WHERE and RETURN clauses are declared in the pattern, and that no variable names both a node and a relationship
+func (q *Query) check() error {
+	kinds := map[string]string{}
+	for _, node := range q.nodes {
+		if node.variable != "" {
+			kinds[node.variable] = "node"
+		}
+	}
+	for _, rel := range q.rels {
+		if rel.variable == "" {
+			continue
+		}
+		if kinds[rel.variable] != "" {
+			return fmt.Errorf("%w; variable %s is used more than once for a relationship, or for both a node and a relationship", ErrSyntax, rel.variable)
+		}
+		kinds[rel.variable] = "relationship"
+	}
+	operands := []operand{}
+	for _, cond := range q.where {
+		operands = append(operands, cond.left, cond.right)
+	}
+	for _, item := range q.returns {
+		operands = append(operands, item.value)
+	}
+	for _, o := range operands {
+		if o.isLiteral {
+			continue
+		}
+		if _, ok := kinds[o.variable]; !ok {
+			return fmt.Errorf("%w; variable %s is not declared in the MATCH clause", ErrSyntax, o.variable)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,483 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mimatache/cyscale/internal/graph"
+)
+
+var (
+	ErrSyntax = errors.New("invalid query")
+)
+
+// operators are the comparisons allowed in the WHERE clause
+var operators = []string{"<>", "<=", ">=", "=", "<", ">", "CONTAINS"}
+
+// Query is a parsed query, that can be run against any graph
+type Query struct {
+	// nodes and rels form the pattern: rels[i] connects nodes[i] to nodes[i+1]
+	nodes    []nodePattern
+	rels     []relPattern
+	where    []condition
+	distinct bool
+	returns  []returnItem
+	limit    int
+}
+
+// Parse reads a query having the form:
+//
+//	MATCH (vm:vm)-[:using]->(i:interface)-[r:part_of]->(sg:securityGroup {direction: "inbound"})
+//	WHERE sg.ipList CONTAINS "0.0.0.0/0" AND i.vpcID <> "vpc-1"
+//	RETURN DISTINCT vm.name AS vm, sg LIMIT 10
+//
+// Nodes and relationships can have a variable, a label and properties, and all of them are optional: () matches any node, and -- any relationship, regardless of its direction.
+// Relationships can match one of several labels, e.g. -[:using|part_of]->. Node properties are matched against the top level fields of the json body,
+// and relationship properties against the properties of the relationship.
+// Fields are read with variable.field, going into nested objects with variable.field.nested. If the body does not have the field, name, label, namespace and id return the attributes of the node,
+// while label and id return those of a relationship. A variable on its own returns the reference of a node (namespace:name), or the label of a relationship.
+// Keywords are not case sensitive
+func Parse(input string) (*Query, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	q, err := (&parser{tokens: tokens}).parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if err := q.check(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// Result holds the values returned by a query, one row for each match of the pattern. Rows are sorted by their values, column by column
+type Result struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// Records returns each row as a map from the column names to the values
+func (r *Result) Records() []map[string]interface{} {
+	records := make([]map[string]interface{}, 0, len(r.Rows))
+	for _, row := range r.Rows {
+		record := make(map[string]interface{}, len(row))
+		for i, value := range row {
+			record[r.Columns[i]] = value
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// Run finds all the matches of the query in a view of the graph, so that changes made while it runs do not affect the result.
+// The search stops with the context's error if the context is done before it ends, and the store's error is returned if it fails to read the graph
+func (q *Query) Run(ctx context.Context, grf *graph.Graph) (*Result, error) {
+	m := &matcher{
+		ctx:      ctx,
+		grf:      grf.Snapshot(),
+		query:    q,
+		filters:  make([][]graph.NodeFilter, len(q.nodes)),
+		nodeVars: map[string]int{},
+		relVars:  map[string]int{},
+		bodies:   map[string]interface{}{},
+		seen:     map[string]struct{}{},
+		rows:     [][]interface{}{},
+	}
+	for i, node := range q.nodes {
+		m.filters[i] = m.nodeFilters(node)
+		if _, ok := m.nodeVars[node.variable]; node.variable != "" && !ok {
+			m.nodeVars[node.variable] = i
+		}
+	}
+	for i, rel := range q.rels {
+		if rel.variable != "" {
+			m.relVars[rel.variable] = i
+		}
+	}
+
+	for _, node := range m.grf.ListNodes(m.filters[0]...) {
+		m.nodes = append(m.nodes[:0], node)
+		m.rels = m.rels[:0]
+		if err := m.extend(0); err != nil {
+			return nil, fmt.Errorf("query stopped; %w", err)
+		}
+	}
+	// nodes and relationships the store failed to read are missing from the matches
+	if err := m.grf.Err(); err != nil {
+		return nil, fmt.Errorf("could not read the graph; %w", err)
+	}
+
+	sort.SliceStable(m.rows, func(i, j int) bool {
+		return lessRow(m.rows[i], m.rows[j])
+	})
+	if q.limit > 0 && len(m.rows) > q.limit {
+		m.rows = m.rows[:q.limit]
+	}
+	result := &Result{Columns: make([]string, 0, len(q.returns)), Rows: m.rows}
+	for _, item := range q.returns {
+		result.Columns = append(result.Columns, item.name)
+	}
+	return result, nil
+}
+
+// matcher holds the state of a search for the matches of a query. nodes and rels hold the part of the pattern matched so far
+type matcher struct {
+	ctx   context.Context
+	grf   *graph.Graph
+	query *Query
+	// filters hold the filters for each node of the pattern
+	filters [][]graph.NodeFilter
+	// nodeVars and relVars hold the position in the pattern where each variable first appears
+	nodeVars map[string]int
+	relVars  map[string]int
+	// bodies caches the decoded bodies of the nodes, by their ID
+	bodies map[string]interface{}
+	nodes  []graph.Node
+	rels   []graph.Relationship
+	// seen holds the rows already returned, when only distinct rows are returned
+	seen map[string]struct{}
+	rows [][]interface{}
+}
+
+// nodeFilters turns a node of the pattern into filters. The label and the fields holding text or a bool are turned into the graph's own filters,
+// so that the nodes can be looked up in the graph's indexes. Since field filters also match lists containing the value, the fields are checked again afterwards
+func (m *matcher) nodeFilters(node nodePattern) []graph.NodeFilter {
+	filters := []graph.NodeFilter{}
+	if node.label != "" {
+		filters = append(filters, graph.FilterNodesByLabel(node.label))
+	}
+	for key, want := range node.properties {
+		// numbers are left out, since they are equal to the strings holding them, while the graph compares values by their json encoding
+		_, isNumber := want.(float64)
+		_, holdsNumber := parseNumber(want)
+		if want == nil || isNumber || holdsNumber {
+			continue
+		}
+		filters = append(filters, graph.FilterNodesByField(key, want))
+	}
+	if len(node.properties) > 0 {
+		filters = append(filters, graph.FilterNodes(func(n graph.Node) bool {
+			body := m.body(n)
+			for key, want := range node.properties {
+				got, _ := lookup(body, []string{key})
+				if !equal(got, want) {
+					return false
+				}
+			}
+			return true
+		}))
+	}
+	return filters
+}
+
+// step is a relationship followed from a node, and the node it leads to
+type step struct {
+	rel graph.Relationship
+	to  string
+}
+
+// extend matches the rest of the pattern, starting from the i-th node, which is already matched
+func (m *matcher) extend(i int) error {
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+	if i == len(m.query.rels) {
+		m.emit()
+		return nil
+	}
+	for _, s := range m.follow(m.nodes[i], m.query.rels[i]) {
+		if m.used(s.rel) {
+			continue
+		}
+		next, err := m.grf.GetNodeByID(s.to)
+		if err != nil {
+			continue
+		}
+		if !m.matches(i+1, next) {
+			continue
+		}
+		m.rels = append(m.rels, s.rel)
+		m.nodes = append(m.nodes, next)
+		err = m.extend(i + 1)
+		m.rels = m.rels[:i]
+		m.nodes = m.nodes[:i+1]
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// follow returns the relationships of the node matching the relationship in the pattern
+func (m *matcher) follow(node graph.Node, pattern relPattern) []step {
+	steps := []step{}
+	seen := map[string]struct{}{}
+	add := func(rel graph.Relationship, to string) {
+		// a relationship from a node to itself is both outgoing and incoming, but should only be followed once
+		if _, ok := seen[rel.ID]; ok {
+			return
+		}
+		seen[rel.ID] = struct{}{}
+		for key, want := range pattern.properties {
+			got, ok := rel.Properties[key]
+			if !ok && want != nil || ok && !equal(got, want) {
+				return
+			}
+		}
+		steps = append(steps, step{rel: rel, to: to})
+	}
+	if pattern.direction == graph.Outbound || pattern.direction == graph.Both {
+		for _, rel := range m.grf.Outgoing(node.GetID(), pattern.labels...) {
+			add(rel, rel.To)
+		}
+	}
+	if pattern.direction == graph.Inbound || pattern.direction == graph.Both {
+		for _, rel := range m.grf.Incoming(node.GetID(), pattern.labels...) {
+			add(rel, rel.From)
+		}
+	}
+	return steps
+}
+
+// used returns true if the relationship was already matched by an earlier part of the pattern
+func (m *matcher) used(rel graph.Relationship) bool {
+	for _, r := range m.rels {
+		if r.ID == rel.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if the node matches the i-th node of the pattern, and is the same node as the one matched earlier by the same variable
+func (m *matcher) matches(i int, node graph.Node) bool {
+	for _, filter := range m.filters[i] {
+		if !filter.Match(node) {
+			return false
+		}
+	}
+	if first, ok := m.nodeVars[m.query.nodes[i].variable]; ok && first < i {
+		return m.nodes[first].GetID() == node.GetID()
+	}
+	return true
+}
+
+// emit adds a row to the result if the current match satisfies the WHERE clause
+func (m *matcher) emit() {
+	for _, cond := range m.query.where {
+		if !evaluate(m.value(cond.left), cond.op, m.value(cond.right)) {
+			return
+		}
+	}
+	row := make([]interface{}, 0, len(m.query.returns))
+	for _, item := range m.query.returns {
+		row = append(row, m.value(item.value))
+	}
+	if m.query.distinct {
+		key := render(row)
+		if _, ok := m.seen[key]; ok {
+			return
+		}
+		m.seen[key] = struct{}{}
+	}
+	m.rows = append(m.rows, row)
+}
+
+// value returns the value of the operand for the current match
+func (m *matcher) value(o operand) interface{} {
+	if o.isLiteral {
+		return o.literal
+	}
+	if i, ok := m.nodeVars[o.variable]; ok {
+		return m.nodeValue(m.nodes[i], o.path)
+	}
+	return relValue(m.rels[m.relVars[o.variable]], o.path)
+}
+
+func (m *matcher) nodeValue(node graph.Node, path []string) interface{} {
+	if len(path) == 0 {
+		return node.Reference()
+	}
+	if value, ok := lookup(m.body(node), path); ok {
+		return value
+	}
+	if len(path) > 1 {
+		return nil
+	}
+	switch path[0] {
+	case "name":
+		return node.GetName()
+	case "label":
+		return node.GetLabel()
+	case "namespace":
+		return node.GetNamespace()
+	case "id":
+		return node.GetID()
+	}
+	return nil
+}
+
+func relValue(rel graph.Relationship, path []string) interface{} {
+	if len(path) == 0 {
+		return rel.Label
+	}
+	if len(path) > 1 {
+		return nil
+	}
+	if value, ok := rel.Properties[path[0]]; ok {
+		return value
+	}
+	switch path[0] {
+	case "label":
+		return rel.Label
+	case "id":
+		return rel.ID
+	}
+	return nil
+}
+
+// body returns the decoded json body of the node, or nil if the body is empty or not valid json
+func (m *matcher) body(node graph.Node) interface{} {
+	if body, ok := m.bodies[node.GetID()]; ok {
+		return body
+	}
+	var body interface{}
+	if len(node.Body) > 0 {
+		if err := json.Unmarshal(node.Body, &body); err != nil {
+			body = nil
+		}
+	}
+	m.bodies[node.GetID()] = body
+	return body
+}
+
+// lookup follows the path through nested json objects
+func lookup(value interface{}, path []string) (interface{}, bool) {
+	for _, field := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[field]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// evaluate applies the comparison to the two values. Comparing values of different types is false, except for numbers and strings holding numbers
+func evaluate(left interface{}, op string, right interface{}) bool {
+	switch strings.ToUpper(op) {
+	case "=":
+		return equal(left, right)
+	case "<>":
+		return !equal(left, right)
+	case "<":
+		c, ok := compare(left, right)
+		return ok && c < 0
+	case "<=":
+		c, ok := compare(left, right)
+		return ok && c <= 0
+	case ">":
+		c, ok := compare(left, right)
+		return ok && c > 0
+	case ">=":
+		c, ok := compare(left, right)
+		return ok && c >= 0
+	case "CONTAINS":
+		switch l := left.(type) {
+		case string:
+			r, ok := right.(string)
+			return ok && strings.Contains(l, r)
+		case []interface{}:
+			for _, item := range l {
+				if equal(item, right) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func equal(a, b interface{}) bool {
+	if x, y, ok := numbers(a, b); ok {
+		return x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare orders two numbers or two strings. ok is false if the values can not be ordered
+func compare(a, b interface{}) (c int, ok bool) {
+	if x, y, ok := numbers(a, b); ok {
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	x, xOK := a.(string)
+	y, yOK := b.(string)
+	if !xOK || !yOK {
+		return 0, false
+	}
+	return strings.Compare(x, y), true
+}
+
+// numbers returns both values as numbers, if at least one of them is a number and the other one is either a number or a string holding a number.
+// This allows comparing the numbers in json bodies with relationship properties, which are strings
+func numbers(a, b interface{}) (x, y float64, ok bool) {
+	x, xOK := a.(float64)
+	y, yOK := b.(float64)
+	if !xOK && !yOK {
+		return 0, 0, false
+	}
+	if !xOK {
+		x, xOK = parseNumber(a)
+	}
+	if !yOK {
+		y, yOK = parseNumber(b)
+	}
+	return x, y, xOK && yOK
+}
+
+func parseNumber(value interface{}) (float64, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(s, 64)
+	return number, err == nil
+}
+
+func lessRow(a, b []interface{}) bool {
+	for i := range a {
+		c, ok := compare(a[i], b[i])
+		if !ok {
+			c = strings.Compare(render(a[i]), render(b[i]))
+		}
+		if c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+// render encodes the value as json, so that any value can be compared or used as a key
+func render(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
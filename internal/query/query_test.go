@@ -0,0 +1,178 @@
+package query_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mimatache/cyscale/internal/graph"
+	"github.com/mimatache/cyscale/internal/query"
+)
+
+// assets builds a graph with two VMs, each using an interface that is part of a security group
+func assets(t *testing.T) *graph.Graph {
+	grf := graph.New()
+	vm1, _ := grf.InsertNode("VM_1", "vm", []byte(`{"name":"VM_1","vpcID":"vpc-1"}`))
+	vm2, _ := grf.InsertNode("VM_2", "vm", []byte(`{"name":"VM_2","vpcID":"vpc-2"}`))
+	intf1, _ := grf.InsertNode("eni-1", "interface", []byte(`{"name":"eni-1","vpcID":"vpc-1"}`))
+	intf2, _ := grf.InsertNode("eni-2", "interface", []byte(`{"name":"eni-2","vpcID":"vpc-2"}`))
+	sg1, _ := grf.InsertNode("sg-1", "securityGroup", []byte(`{"direction":"inbound","exposedPorts":[80,443],"ipList":["0.0.0.0/0"],"rule":{"priority":10}}`))
+	sg2, _ := grf.InsertNode("sg-2", "securityGroup", []byte(`{"direction":"outbound","exposedPorts":[22],"ipList":["10.0.0.0/8"],"rule":{"priority":20}}`))
+	grf.InsertNode("sg-3", "securityGroup", []byte{})
+	for _, rel := range []struct {
+		from, to graph.Node
+		label    string
+		props    map[string]string
+	}{
+		{vm1, intf1, "using", nil},
+		{vm2, intf2, "using", nil},
+		{intf1, sg1, "part_of", map[string]string{"port": "80"}},
+		{intf2, sg2, "part_of", map[string]string{"port": "22"}},
+		{vm1, sg1, "part_of", nil},
+	} {
+		_, err := grf.AddRelationshipWithProperties(rel.from.GetID(), rel.to.GetID(), rel.label, rel.props)
+		assert.NoError(t, err)
+	}
+	return grf
+}
+
+func run(t *testing.T, grf *graph.Graph, expr string) *query.Result {
+	q, err := query.Parse(expr)
+	assert.NoError(t, err)
+	if err != nil {
+		t.FailNow()
+	}
+	result, err := q.Run(context.Background(), grf)
+	assert.NoError(t, err)
+	return result
+}
+
+func Test_Query_Path(t *testing.T) {
+	result := run(t, assets(t), `MATCH (vm:vm)-[:using]->(i:interface)-[:part_of]->(sg:securityGroup {direction:"inbound"}) RETURN vm.name`)
+	assert.Equal(t, []string{"vm.name"}, result.Columns)
+	assert.Equal(t, [][]interface{}{{"VM_1"}}, result.Rows)
+}
+
+func Test_Query_Where(t *testing.T) {
+	grf := assets(t)
+
+	result := run(t, grf, `match (sg:securityGroup) where sg.ipList contains "0.0.0.0/0" return sg`)
+	assert.Equal(t, [][]interface{}{{"sg-1"}}, result.Rows)
+
+	result = run(t, grf, `MATCH (sg:securityGroup) WHERE sg.exposedPorts CONTAINS 22 RETURN sg`)
+	assert.Equal(t, [][]interface{}{{"sg-2"}}, result.Rows)
+
+	result = run(t, grf, `MATCH (sg:securityGroup) WHERE sg.rule.priority >= 10 AND sg.rule.priority < 20 RETURN sg, sg.rule.priority AS priority`)
+	assert.Equal(t, []string{"sg", "priority"}, result.Columns)
+	assert.Equal(t, [][]interface{}{{"sg-1", 10.0}}, result.Rows)
+
+	// placeholders do not have a body, so only the attributes of the node can be read
+	result = run(t, grf, `MATCH (sg:securityGroup) WHERE sg.direction = null RETURN sg.name, sg.label`)
+	assert.Equal(t, [][]interface{}{{"sg-3", "securityGroup"}}, result.Rows)
+
+	result = run(t, grf, `MATCH (vm:vm) WHERE vm.vpcID <> "vpc-1" RETURN vm`)
+	assert.Equal(t, [][]interface{}{{"VM_2"}}, result.Rows)
+}
+
+func Test_Query_Relationships(t *testing.T) {
+	grf := assets(t)
+
+	// relationship properties are strings, but can be compared with numbers
+	result := run(t, grf, `MATCH (i:interface)-[r:part_of]->(sg) WHERE r.port > 25 RETURN i, r, r.port`)
+	assert.Equal(t, [][]interface{}{{"eni-1", "part_of", "80"}}, result.Rows)
+
+	result = run(t, grf, `MATCH (sg:securityGroup)<-[:part_of {port: 22}]-(i) RETURN i`)
+	assert.Equal(t, [][]interface{}{{"eni-2"}}, result.Rows)
+
+	result = run(t, grf, `MATCH (vm:vm)-[:using|part_of]->(x) RETURN vm, x`)
+	assert.Equal(t, [][]interface{}{{"VM_1", "eni-1"}, {"VM_1", "sg-1"}, {"VM_2", "eni-2"}}, result.Rows)
+
+	// without an arrow, relationships are followed either way
+	result = run(t, grf, `MATCH (sg {direction: "inbound"})--(x) RETURN x`)
+	assert.Equal(t, [][]interface{}{{"VM_1"}, {"eni-1"}}, result.Rows)
+}
+
+func Test_Query_SameVariable(t *testing.T) {
+	grf := assets(t)
+	// the VMs that are part of a security group both directly and through one of their interfaces
+	result := run(t, grf, `MATCH (vm:vm)-[:part_of]->(sg)<-[:part_of]-(i)<-[:using]-(vm) RETURN vm, sg`)
+	assert.Equal(t, [][]interface{}{{"VM_1", "sg-1"}}, result.Rows)
+}
+
+func Test_Query_DistinctAndLimit(t *testing.T) {
+	grf := assets(t)
+
+	result := run(t, grf, `MATCH (vm:vm)-->(x) RETURN vm`)
+	assert.Equal(t, [][]interface{}{{"VM_1"}, {"VM_1"}, {"VM_2"}}, result.Rows)
+
+	result = run(t, grf, `MATCH (vm:vm)-->(x) RETURN DISTINCT vm`)
+	assert.Equal(t, [][]interface{}{{"VM_1"}, {"VM_2"}}, result.Rows)
+
+	result = run(t, grf, `MATCH (n) RETURN n.label AS label, n LIMIT 2`)
+	assert.Equal(t, [][]interface{}{{"interface", "eni-1"}, {"interface", "eni-2"}}, result.Rows)
+	assert.Equal(t, []map[string]interface{}{{"label": "interface", "n": "eni-1"}, {"label": "interface", "n": "eni-2"}}, result.Records())
+}
+
+func Test_Query_Namespaces(t *testing.T) {
+	grf := graph.New()
+	_, err := grf.UpsertNodeInNamespace("prod", "VM_1", "vm", []byte(`{}`))
+	assert.NoError(t, err)
+	result := run(t, grf, `MATCH (vm:vm) RETURN vm, vm.namespace, vm.name`)
+	assert.Equal(t, [][]interface{}{{"prod:VM_1", "prod", "VM_1"}}, result.Rows)
+}
+
+func Test_Query_Canceled(t *testing.T) {
+	q, err := query.Parse(`MATCH (a)-->(b) RETURN a`)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = q.Run(ctx, assets(t))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func Test_Query_FailedReads(t *testing.T) {
+	store, err := graph.NewTempDiskStore(t.TempDir())
+	assert.NoError(t, err)
+	grf, err := graph.NewWithStore(store)
+	assert.NoError(t, err)
+	grf.InsertNode("VM_1", "vm", []byte(`{"name":"VM_1"}`))
+	assert.NoError(t, grf.Close())
+
+	q, err := query.Parse(`MATCH (vm:vm) RETURN vm`)
+	assert.NoError(t, err)
+	_, err = q.Run(context.Background(), grf)
+	assert.ErrorIs(t, err, graph.ErrStoreClosed)
+}
+
+func Test_Query_Syntax(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`MATCH (vm:vm)`,
+		`MATCH (vm:vm RETURN vm`,
+		`MATCH (vm)<-[:using]->(i) RETURN vm`,
+		`MATCH (vm) WHERE vm.name ~ "a" RETURN vm`,
+		`MATCH (vm) RETURN other`,
+		`MATCH (vm)-[vm]->(i) RETURN vm`,
+		`MATCH (vm {name: "VM_1}) RETURN vm`,
+		`MATCH (vm) RETURN vm LIMIT many`,
+		`MATCH (vm) RETURN vm extra`,
+	} {
+		_, err := query.Parse(expr)
+		assert.ErrorIs(t, err, query.ErrSyntax, expr)
+	}
+}
+
+func Test_Query_IndexedFields(t *testing.T) {
+	grf := assets(t)
+	grf.IndexField("securityGroup", "direction")
+	grf.IndexField("vm", "vpcID")
+
+	result := run(t, grf, `MATCH (vm:vm {vpcID: "vpc-1"})-[:using]->(i)-[:part_of]->(sg {direction: "inbound"}) RETURN vm, sg`)
+	assert.Equal(t, [][]interface{}{{"VM_1", "sg-1"}}, result.Rows)
+
+	// numbers match the strings holding them, whether the field is indexed or not
+	grf.InsertNode("VM_3", "vm", []byte(`{"vpcID":"3"}`))
+	result = run(t, grf, `MATCH (vm:vm {vpcID: 3}) RETURN vm`)
+	assert.Equal(t, [][]interface{}{{"VM_3"}}, result.Rows)
+}